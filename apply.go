@@ -0,0 +1,85 @@
+package traefik_plugin_parameters
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Apply applies the rules targeting the query to a copy of the given values and returns the result,
+// e.g. to test a configuration without running an HTTP server. The given values are left untouched.
+// As there is no request, conditions on the request like methods, paths or schemes are ignored
+// and request data like headers is empty, conditions on the query are evaluated as usual.
+// The modification hook, maxParams, originalQueryParam and the options normalizing the query apply as for requests,
+// the options encoding the query do not, as the values are returned decoded.
+func (q *QueryModification) Apply(values url.Values) url.Values {
+	qry := make(url.Values, len(values))
+	for key, vs := range values {
+		qry[key] = append([]string(nil), vs...)
+	}
+
+	if q.config.MaxParams > 0 && len(qry) > q.config.MaxParams {
+		q.logger.Warnf("msg=\"query exceeds maxParams, leaving the values unchanged\" params=%d", len(qry))
+		return qry
+	}
+
+	// without a raw query, the raw names and values are derived from the decoded ones
+	state := &requestState{header: http.Header{}, rawNames: map[string][]string{}, rawValues: map[string][]string{}, counter: &q.counter}
+	q.applyQueryRules(context.Background(), q.rules, qry, state)
+	q.normalizeQuery(qry, nil)
+
+	if q.config.OriginalQueryParam != "" {
+		qry.Set(q.config.OriginalQueryParam, values.Encode())
+	}
+	return qry
+}
+
+// applyQueryRules applies the given rules targeting the query to the given query, in order.
+// Conditions on the request are only evaluated if the state holds a request.
+// It returns whether any param was changed.
+func (q *QueryModification) applyQueryRules(ctx context.Context, rules []*rule, qry url.Values, state *requestState) bool {
+	modified := false
+	for _, r := range rules {
+		if !r.enabled() || !r.active() || r.config.Target != "" && r.config.Target != queryTarget {
+			continue
+		}
+		if state.req != nil && !r.appliesTo(state.req) {
+			continue
+		}
+		if r.hasQueryCondition() && !r.queryConditionMet(qry) {
+			continue
+		}
+
+		changed := q.applyQueryRule(ctx, r, qry, state)
+		modified = modified || len(changed) > 0
+	}
+	return modified
+}
+
+// applyQueryRule applies the given rule to the given query, consulting the modification hook.
+// It returns the names of the changed params.
+func (q *QueryModification) applyQueryRule(ctx context.Context, r *rule, qry url.Values, state *requestState) []string {
+	if r.config.Type == copyToHeaderType {
+		return q.copyToHeaderWithHook(ctx, r, qry, state)
+	}
+	return q.applyWithHook(ctx, qry, func() []string { return r.modifyParams(qry, state) })
+}
+
+// normalizeQuery applies the options normalizing the whole query after the rules, like nameCase or dedupe.
+// The given map tells the params replaced by add-or-replace, see convertNameCase. It returns whether the query was changed.
+func (q *QueryModification) normalizeQuery(qry url.Values, replaced map[string]string) bool {
+	modified := false
+	if q.config.NameCase != "" && q.convertNameCase(qry, replaced) {
+		modified = true
+	}
+	if q.config.Dedupe && q.dedupeValues(qry) {
+		modified = true
+	}
+	if q.config.SortValues && q.sortValues(qry) {
+		modified = true
+	}
+	if q.config.CollapseRepeated && q.collapseValues(qry) {
+		modified = true
+	}
+	return modified
+}
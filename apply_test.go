@@ -0,0 +1,113 @@
+package traefik_plugin_parameters_test
+
+import (
+	"net/url"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestApply(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		rules    []traefik_plugin_parameters.RuleConfig
+		values   url.Values
+		expected string
+	}{
+		{
+			desc:     "add",
+			rules:    []traefik_plugin_parameters.RuleConfig{{Type: "add", ParamName: "a", NewValue: "1"}},
+			values:   url.Values{"b": {"2"}},
+			expected: "a=1&b=2",
+		},
+		{
+			desc:     "delete by regex",
+			rules:    []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamNameRegex: "^utm_"}},
+			values:   url.Values{"utm_source": {"x"}, "id": {"1"}},
+			expected: "id=1",
+		},
+		{
+			desc: "multiple rules",
+			rules: []traefik_plugin_parameters.RuleConfig{
+				{Type: "rename", ParamName: "user_id", NewName: "uid"},
+				{Type: "modify", ParamName: "uid", NewValue: "u-$1"},
+			},
+			values:   url.Values{"user_id": {"42"}},
+			expected: "uid=u-42",
+		},
+		{
+			desc:     "header rules ignored",
+			rules:    []traefik_plugin_parameters.RuleConfig{{Type: "delete", Target: "header", ParamName: "a"}},
+			values:   url.Values{"a": {"1"}},
+			expected: "a=1",
+		},
+		{
+			desc:     "query condition",
+			rules:    []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "debug", ConditionParam: "env", ConditionValueRegex: "^prod$"}},
+			values:   url.Values{"debug": {"1"}, "env": {"dev"}},
+			expected: "debug=1&env=dev",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.Rules = test.rules
+			q := newHandler(t, cfg, nil)
+
+			if result := q.Apply(test.values).Encode(); result != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestApply_LeavesInputUntouched(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.NewValue = "new"
+	q := newHandler(t, cfg, nil)
+	values := url.Values{"a": {"old"}}
+
+	q.Apply(values)
+
+	if values.Get("a") != "old" {
+		t.Errorf("Expected input to be untouched, got %v", values)
+	}
+}
+
+func TestApply_ConsultsHook(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "a"}, {Type: "delete", ParamName: "b"}}
+	q := newHandler(t, cfg, nil)
+	q.SetModificationHook(&vetoHook{vetoed: "a"})
+
+	if result := q.Apply(url.Values{"a": {"1"}, "b": {"2"}}).Encode(); result != "a=1" {
+		t.Errorf("Expected the vetoed param to be kept, got %s", result)
+	}
+}
+
+func TestApply_MaxParams(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.MaxParams = 1
+	q := newHandler(t, cfg, nil)
+
+	if result := q.Apply(url.Values{"a": {"1"}, "b": {"2"}}).Encode(); result != "a=1&b=2" {
+		t.Errorf("Expected the values to be unchanged, got %s", result)
+	}
+}
+
+func TestApply_OriginalQueryParam(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.OriginalQueryParam = "original"
+	q := newHandler(t, cfg, nil)
+
+	if result := q.Apply(url.Values{"a": {"1"}, "b": {"2"}}).Encode(); result != "b=2&original=a%3D1%26b%3D2" {
+		t.Errorf("Expected the original query to be added, got %s", result)
+	}
+}
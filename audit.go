@@ -0,0 +1,63 @@
+package traefik_plugin_parameters
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// auditEntry is the JSON line written by auditLog for each modified request
+type auditEntry struct {
+	Timestamp     string              `json:"timestamp"`
+	Plugin        string              `json:"plugin"`
+	Path          string              `json:"path"`
+	Modifications []auditModification `json:"modifications"`
+}
+
+// auditModification describes the change of a single param by a rule
+type auditModification struct {
+	Rule      string           `json:"rule"`
+	Type      modificationType `json:"type"`
+	Param     string           `json:"param"`
+	OldValues []string         `json:"oldValues"`
+	NewValues []string         `json:"newValues"`
+}
+
+// snapshotParams returns a copy of the given params if the changes are recorded, e.g. for the audit log, nil otherwise
+func snapshotParams(record bool, params map[string][]string) map[string][]string {
+	if !record {
+		return nil
+	}
+	return cloneParams(params)
+}
+
+// auditModifications returns the modifications of the changed params, given their values before and after the rule
+func auditModifications(r *rule, changed []string, before, after map[string][]string) []auditModification {
+	modifications := make([]auditModification, 0, len(changed))
+	for _, param := range changed {
+		modifications = append(modifications, auditModification{
+			Rule:      r.name,
+			Type:      r.config.Type,
+			Param:     param,
+			OldValues: before[param],
+			NewValues: after[param],
+		})
+	}
+	return modifications
+}
+
+// writeAuditLog writes the given modifications of the request with the given path as a single JSON line.
+// Like the other messages it is written to the output of the standard logger, but without any prefix.
+func (q *QueryModification) writeAuditLog(path string, modifications []auditModification) {
+	line, err := json.Marshal(auditEntry{
+		Timestamp:     time.Now().UTC().Format(time.RFC3339Nano),
+		Plugin:        q.name,
+		Path:          path,
+		Modifications: modifications,
+	})
+	if err != nil {
+		q.logger.Warnf("msg=\"could not encode audit log entry\" error=%q", err)
+		return
+	}
+	_, _ = log.Writer().Write(append(line, '\n'))
+}
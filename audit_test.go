@@ -0,0 +1,94 @@
+package traefik_plugin_parameters_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+type auditEntry struct {
+	Timestamp     string `json:"timestamp"`
+	Plugin        string `json:"plugin"`
+	Path          string `json:"path"`
+	Modifications []struct {
+		Rule      string   `json:"rule"`
+		Type      string   `json:"type"`
+		Param     string   `json:"param"`
+		OldValues []string `json:"oldValues"`
+		NewValues []string `json:"newValues"`
+	} `json:"modifications"`
+}
+
+func TestAuditLog_Modification(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.LogLevel = "none"
+	cfg.AuditLog = true
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "modify", ParamName: "a", NewValue: "x", Name: "rewrite-a"},
+		{Type: "delete", ParamName: "b"},
+	}
+
+	logged := serveAndCaptureLog(t, cfg, "a=1&a=2&b=3&c=4")
+
+	lines := strings.Split(strings.TrimSpace(logged), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected a single line, got %q", logged)
+	}
+	var entry auditEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Expected JSON, got %s: %v", lines[0], err)
+	}
+
+	if _, err := time.Parse(time.RFC3339Nano, entry.Timestamp); err != nil {
+		t.Errorf("Expected RFC 3339 timestamp, got %s", entry.Timestamp)
+	}
+	if entry.Plugin != "query-modification-plugin" || entry.Path != "" {
+		t.Errorf("Expected plugin and path, got %s %s", entry.Plugin, entry.Path)
+	}
+	if len(entry.Modifications) != 2 {
+		t.Fatalf("Expected 2 modifications, got %+v", entry.Modifications)
+	}
+
+	modify, del := entry.Modifications[0], entry.Modifications[1]
+	if modify.Rule != "rewrite-a" || del.Rule != "rules[1]" {
+		t.Errorf("Expected the rule names rewrite-a and rules[1], got %s and %s", modify.Rule, del.Rule)
+	}
+	if modify.Type != "modify" || modify.Param != "a" ||
+		!reflect.DeepEqual(modify.OldValues, []string{"1", "2"}) || !reflect.DeepEqual(modify.NewValues, []string{"x", "x"}) {
+		t.Errorf("Unexpected modification %+v", modify)
+	}
+	if del.Type != "delete" || del.Param != "b" || !reflect.DeepEqual(del.OldValues, []string{"3"}) || del.NewValues != nil {
+		t.Errorf("Unexpected modification %+v", del)
+	}
+}
+
+func TestAuditLog_NothingChanged(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.LogLevel = "none"
+	cfg.AuditLog = true
+	cfg.Type = "delete"
+	cfg.ParamName = "b"
+
+	logged := serveAndCaptureLog(t, cfg, "a=1")
+
+	if logged != "" {
+		t.Errorf("Expected no audit log, got %s", logged)
+	}
+}
+
+func TestAuditLog_Disabled(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.LogLevel = "none"
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+
+	logged := serveAndCaptureLog(t, cfg, "a=1")
+
+	if logged != "" {
+		t.Errorf("Expected no audit log, got %s", logged)
+	}
+}
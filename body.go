@@ -0,0 +1,84 @@
+package traefik_plugin_parameters
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const formContentType = "application/x-www-form-urlencoded"
+
+// errBodyTooLarge is returned for bodies exceeding maxBodyBytes
+var errBodyTooLarge = errors.New("body exceeds maxBodyBytes")
+
+// isFormRequest reports whether the request carries a form encoded body
+func isFormRequest(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == formContentType
+}
+
+// readFormBody reads and parses the form encoded body of the given request, reading at most maxBytes if positive.
+// The body is restored afterwards, so it can be read again by the next handler.
+func readFormBody(req *http.Request, maxBytes int64) (url.Values, []byte, error) {
+	body, err := readBody(req, maxBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body == nil {
+		return url.Values{}, nil, nil
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, body, err
+	}
+	return form, body, nil
+}
+
+// readBody reads the body of the given request, reading at most maxBytes if positive.
+// The body is restored afterwards, so it can be read again by the next handler. Absent bodies are returned as nil.
+func readBody(req *http.Request, maxBytes int64) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	var reader io.Reader = req.Body
+	if maxBytes > 0 {
+		// one more byte is read to tell a body of exactly maxBytes from a larger one
+		reader = io.LimitReader(req.Body, maxBytes+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err == nil && maxBytes > 0 && int64(len(body)) > maxBytes {
+		err = errBodyTooLarge
+	}
+	if err != nil {
+		// keep the part already read in front of the rest of the body
+		req.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), req.Body), Closer: req.Body}
+		return nil, err
+	}
+	_ = req.Body.Close()
+	setBody(req, body)
+	return body, nil
+}
+
+// setBody replaces the body of the given request and updates its content length
+func setBody(req *http.Request, body []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	if req.Header.Get("Content-Length") != "" {
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+}
+
+// readCloser combines a reader with the closer of the original body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
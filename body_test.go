@@ -0,0 +1,176 @@
+package traefik_plugin_parameters_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestForm_Modify(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.Target = "form"
+	cfg.ParamName = "password"
+	cfg.NewValue = "censored"
+
+	body, contentLength := serveForm(t, cfg, "application/x-www-form-urlencoded", "password=secret&user=john")
+
+	if body != "password=censored&user=john" {
+		t.Errorf("Expected %s, got %s", "password=censored&user=john", body)
+	}
+	if contentLength != int64(len(body)) {
+		t.Errorf("Expected content length %d, got %d", len(body), contentLength)
+	}
+}
+
+func TestForm_ContentTypeWithCharset(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "form"
+	cfg.ParamName = "password"
+
+	body, contentLength := serveForm(t, cfg, "application/x-www-form-urlencoded; charset=utf-8", "password=secret&user=john")
+
+	if body != "user=john" {
+		t.Errorf("Expected %s, got %s", "user=john", body)
+	}
+	if contentLength != int64(len(body)) {
+		t.Errorf("Expected content length %d, got %d", len(body), contentLength)
+	}
+}
+
+func TestForm_OtherContentType(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "form"
+	cfg.ParamName = "password"
+
+	body, _ := serveForm(t, cfg, "application/json", `{"password":"secret"}`)
+
+	if body != `{"password":"secret"}` {
+		t.Errorf("Expected %s, got %s", `{"password":"secret"}`, body)
+	}
+}
+
+func TestForm_EmptyBody(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.Target = "form"
+	cfg.ParamName = "source"
+	cfg.NewValue = "proxy"
+
+	body, contentLength := serveForm(t, cfg, "application/x-www-form-urlencoded", "")
+
+	if body != "source=proxy" {
+		t.Errorf("Expected %s, got %s", "source=proxy", body)
+	}
+	if contentLength != int64(len(body)) {
+		t.Errorf("Expected content length %d, got %d", len(body), contentLength)
+	}
+}
+
+func TestForm_QueryUntouched(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "form"
+	cfg.ParamName = "a"
+	previous := "a=b"
+	expected := "a=b"
+
+	assertQueryModificationWithMethod(t, cfg, http.MethodPost, previous, expected)
+}
+
+func TestForm_MaxBodyBytesUnderLimit(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "form"
+	cfg.ParamName = "password"
+	cfg.MaxBodyBytes = int64(len("password=secret&user=john"))
+
+	body, _ := serveForm(t, cfg, "application/x-www-form-urlencoded", "password=secret&user=john")
+
+	if body != "user=john" {
+		t.Errorf("Expected %s, got %s", "user=john", body)
+	}
+}
+
+func TestForm_MaxBodyBytesOverLimit(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "form"
+	cfg.ParamName = "password"
+	cfg.MaxBodyBytes = 10
+	previous := "password=secret&user=john"
+
+	body, contentLength := serveForm(t, cfg, "application/x-www-form-urlencoded", previous)
+
+	if body != previous {
+		t.Errorf("Expected %s, got %s", previous, body)
+	}
+	if contentLength != int64(len(previous)) {
+		t.Errorf("Expected content length %d, got %d", len(previous), contentLength)
+	}
+}
+
+func TestForm_MaxBodyBytesRejected(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "form"
+	cfg.ParamName = "password"
+	cfg.MaxBodyBytes = 10
+	cfg.RejectLargeBody = true
+
+	forwarded := false
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) { forwarded = true })
+	handler := newHandler(t, cfg, next)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader("password=secret&user=john"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(recorder, req)
+
+	if forwarded {
+		t.Error("Expected the request not to be forwarded")
+	}
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, recorder.Code)
+	}
+}
+
+func TestForm_ErrorRejectLargeBodyWithoutLimit(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "form"
+	cfg.ParamName = "password"
+	cfg.RejectLargeBody = true
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+// serveForm posts the given body and returns the body and content length read by the next handler.
+func serveForm(t *testing.T, cfg *traefik_plugin_parameters.Config, contentType, body string) (string, int64) {
+	var forwardedBody string
+	var forwardedContentLength int64
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forwardedBody = string(b)
+		forwardedContentLength = req.ContentLength
+	})
+	handler := newHandler(t, cfg, next)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	return forwardedBody, forwardedContentLength
+}
@@ -0,0 +1,120 @@
+package traefik_plugin_parameters
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// cidrMatchMode decides whether a rule applies to the clients within clientCIDRs or to all other clients
+type cidrMatchMode string
+
+const (
+	includeCIDRMode cidrMatchMode = "include"
+	excludeCIDRMode cidrMatchMode = "exclude"
+)
+
+func (m cidrMatchMode) isValid() bool {
+	switch m {
+	case includeCIDRMode, excludeCIDRMode, "":
+		return true
+	}
+
+	return false
+}
+
+// parseClientCIDRs parses the ranges of clientCIDRs and validates the options depending on them
+func parseClientCIDRs(config *RuleConfig) ([]*net.IPNet, error) {
+	if !config.CIDRMatchMode.isValid() {
+		return nil, errors.New("invalid cidrMatchMode, expected include / exclude")
+	}
+	if len(config.ClientCIDRs) == 0 {
+		if config.CIDRMatchMode != "" || config.TrustForwardedFor {
+			return nil, errors.New("cidrMatchMode and trustForwardedFor can only be used together with clientCIDRs")
+		}
+		return nil, nil
+	}
+
+	return parseCIDRs("clientCIDRs", config.ClientCIDRs)
+}
+
+// parseTrustedProxies parses the ranges of trustedProxies, which can only be used together with trustForwardedFor
+func parseTrustedProxies(config *RuleConfig) ([]*net.IPNet, error) {
+	if len(config.TrustedProxies) > 0 && !config.TrustForwardedFor {
+		return nil, errors.New("trustedProxies can only be used together with trustForwardedFor")
+	}
+	return parseCIDRs("trustedProxies", config.TrustedProxies)
+}
+
+// parseCIDRs parses the given address ranges of the given field
+func parseCIDRs(field string, cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// appliesToClient reports whether the rule applies to the client of the given request according to clientCIDRs.
+// A client whose address cannot be determined is treated as being outside of all ranges.
+func (r *rule) appliesToClient(req *http.Request) bool {
+	if len(r.clientNetworks) == 0 {
+		return true
+	}
+
+	inRange := false
+	if ip := r.clientIP(req); ip != nil {
+		inRange = containsIP(r.clientNetworks, ip)
+	} else {
+		r.logger.Debugf("msg=\"could not determine client address\" remoteAddr=%q", req.RemoteAddr)
+	}
+
+	return inRange != (r.config.CIDRMatchMode == excludeCIDRMode)
+}
+
+// clientIP returns the address of the client of the given request, which is the right-most address of the
+// X-Forwarded-For header not within trustedProxies with trustForwardedFor, or the remote address otherwise.
+// The addresses left of it may be sent by the client itself. It returns nil if the address is malformed.
+func (r *rule) clientIP(req *http.Request) net.IP {
+	if r.config.TrustForwardedFor {
+		if forwardedFor := strings.Join(req.Header.Values("X-Forwarded-For"), ","); forwardedFor != "" {
+			return r.forwardedClientIP(strings.Split(forwardedFor, ","))
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		// the remote address may lack the port
+		host = req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// forwardedClientIP returns the right-most of the given X-Forwarded-For addresses not within trustedProxies,
+// or the left-most one if all of them are. It returns nil if an address up to it is malformed.
+func (r *rule) forwardedClientIP(addresses []string) net.IP {
+	var ip net.IP
+	for i := len(addresses) - 1; i >= 0; i-- {
+		ip = net.ParseIP(strings.TrimSpace(addresses[i]))
+		if ip == nil || !containsIP(r.trustedProxies, ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+// containsIP reports whether the given address is within one of the given ranges
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package traefik_plugin_parameters_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestClientCIDRs_Include(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.ClientCIDRs = []string{"10.0.0.0/8", "2001:db8::/32"}
+
+	assertClientModification(t, cfg, "10.1.2.3:1234", "", "a=1")
+	assertClientModification(t, cfg, "[2001:db8::1]:1234", "", "a=1")
+	assertClientModification(t, cfg, "192.168.1.1:1234", "", "a=1&debug=1")
+}
+
+func TestClientCIDRs_Exclude(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.ClientCIDRs = []string{"10.0.0.0/8"}
+	cfg.CIDRMatchMode = "exclude"
+
+	assertClientModification(t, cfg, "10.1.2.3:1234", "", "a=1&debug=1")
+	assertClientModification(t, cfg, "192.168.1.1:1234", "", "a=1")
+}
+
+func TestClientCIDRs_RemoteAddrWithoutPort(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.ClientCIDRs = []string{"10.0.0.0/8"}
+
+	assertClientModification(t, cfg, "10.1.2.3", "", "a=1")
+}
+
+func TestClientCIDRs_MalformedRemoteAddr(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.ClientCIDRs = []string{"10.0.0.0/8"}
+
+	// an unknown client is outside of all ranges
+	assertClientModification(t, cfg, "not-an-address", "", "a=1&debug=1")
+
+	cfg.CIDRMatchMode = "exclude"
+	assertClientModification(t, cfg, "not-an-address", "", "a=1")
+}
+
+func TestClientCIDRs_ForwardedFor(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.ClientCIDRs = []string{"10.0.0.0/8"}
+
+	// the header is ignored unless trusted
+	assertClientModification(t, cfg, "192.168.1.1:1234", "10.1.2.3", "a=1&debug=1")
+
+	cfg.TrustForwardedFor = true
+	assertClientModification(t, cfg, "192.168.1.1:1234", "192.168.1.2, 10.1.2.3", "a=1")
+	assertClientModification(t, cfg, "10.1.2.3:1234", "203.0.113.1", "a=1&debug=1")
+	// the left-most address may be sent by the client, the right-most one is used
+	assertClientModification(t, cfg, "192.168.1.1:1234", "10.1.2.3, 203.0.113.1", "a=1&debug=1")
+	// without the header, the remote address is used
+	assertClientModification(t, cfg, "10.1.2.3:1234", "", "a=1")
+}
+
+func TestClientCIDRs_TrustedProxies(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.ClientCIDRs = []string{"10.0.0.0/8"}
+	cfg.TrustForwardedFor = true
+	cfg.TrustedProxies = []string{"192.168.0.0/16"}
+
+	assertClientModification(t, cfg, "192.168.1.1:1234", "203.0.113.1, 10.1.2.3, 192.168.1.2", "a=1")
+	assertClientModification(t, cfg, "192.168.1.1:1234", "10.1.2.3, 203.0.113.1, 192.168.1.2", "a=1&debug=1")
+	// if all addresses are trusted proxies, the left-most one is used
+	assertClientModification(t, cfg, "192.168.1.1:1234", "192.168.1.3, 192.168.1.2", "a=1&debug=1")
+	// a malformed address is treated as an unknown client
+	assertClientModification(t, cfg, "192.168.1.1:1234", "10.1.2.3, unknown, 192.168.1.2", "a=1&debug=1")
+}
+
+func TestClientCIDRs_Errors(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		config        traefik_plugin_parameters.RuleConfig
+		expectedError string
+	}{
+		{desc: "invalid CIDR", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", ClientCIDRs: []string{"10.0.0.0/8", "10.0.0.1"}}, expectedError: "clientCIDRs[1]: "},
+		{desc: "invalid mode", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", ClientCIDRs: []string{"10.0.0.0/8"}, CIDRMatchMode: "only"}, expectedError: "invalid cidrMatchMode"},
+		{desc: "mode without CIDRs", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", CIDRMatchMode: "exclude"}, expectedError: "can only be used together with clientCIDRs"},
+		{desc: "invalid trusted proxy", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", ClientCIDRs: []string{"10.0.0.0/8"}, TrustForwardedFor: true, TrustedProxies: []string{"proxy"}}, expectedError: "trustedProxies[0]: "},
+		{desc: "trustedProxies without trustForwardedFor", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", ClientCIDRs: []string{"10.0.0.0/8"}, TrustedProxies: []string{"192.168.0.0/16"}}, expectedError: "can only be used together with trustForwardedFor"},
+		{desc: "trustForwardedFor without CIDRs", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", TrustForwardedFor: true}, expectedError: "can only be used together with clientCIDRs"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = test.config
+
+			err := traefik_plugin_parameters.ValidateConfig(cfg)
+			if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("expected error containing %q, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func assertClientModification(t *testing.T, cfg *traefik_plugin_parameters.Config, remoteAddr, forwardedFor, expected string) {
+	t.Helper()
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	handler := newHandler(t, cfg, next)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost?a=1&debug=1", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if req.URL.RawQuery != expected {
+		t.Errorf("Expected %s for client %s, got %s", expected, remoteAddr, req.URL.RawQuery)
+	}
+}
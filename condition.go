@@ -0,0 +1,160 @@
+package traefik_plugin_parameters
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// pathSourceType selects where pathRegex takes the request path from
+type pathSourceType string
+
+const (
+	requestPathSource  pathSourceType = "path"
+	forwardedURISource pathSourceType = "forwarded-uri"
+)
+
+// forwardedURIHeader holds the original request URI behind a ForwardAuth middleware
+const forwardedURIHeader = "X-Forwarded-Uri"
+
+func (p pathSourceType) isValid() bool {
+	switch p {
+	case requestPathSource, forwardedURISource, "":
+		return true
+	}
+
+	return false
+}
+
+// nowFunc returns the current time to check activeFrom and activeUntil against, it is only replaced in tests
+var nowFunc = time.Now
+
+// appliesTo reports whether the given request fulfills all conditions of this rule.
+func (r *rule) appliesTo(req *http.Request) bool {
+	if !r.enabled() || !r.active() {
+		return false
+	}
+
+	if !r.appliesToMethod(req.Method) {
+		return false
+	}
+
+	if !r.appliesToClient(req) {
+		return false
+	}
+
+	if r.pathRegexCompiled != nil {
+		path, ok := r.requestPath(req)
+		if !ok || !r.pathRegexCompiled.MatchString(path) {
+			return false
+		}
+	}
+
+	if r.schemeRegexCompiled != nil && !r.schemeRegexCompiled.MatchString(requestScheme(req)) {
+		return false
+	}
+
+	if r.contentTypeRegexCompiled != nil && !r.contentTypeRegexCompiled.MatchString(req.Header.Get("Content-Type")) {
+		return false
+	}
+
+	if r.acceptRegexCompiled != nil && !r.acceptRegexCompiled.MatchString(req.Header.Get("Accept")) {
+		return false
+	}
+
+	return true
+}
+
+// requestPath returns the path pathRegex is matched against, which is the path of the request
+// or the path of the X-Forwarded-Uri header with pathSource forwarded-uri, e.g. behind a ForwardAuth middleware.
+// It reports false if the header is absent or cannot be parsed.
+func (r *rule) requestPath(req *http.Request) (string, bool) {
+	if r.config.PathSource != forwardedURISource {
+		return req.URL.Path, true
+	}
+
+	forwardedURI := req.Header.Get(forwardedURIHeader)
+	if forwardedURI == "" {
+		return "", false
+	}
+	// the header holds the request URI including the query, only its decoded path is matched
+	u, err := url.ParseRequestURI(forwardedURI)
+	if err != nil {
+		return "", false
+	}
+	return u.Path, true
+}
+
+// enabled reports whether the rule is enabled, which is the default if Enabled is not set.
+func (r *rule) enabled() bool {
+	return r.config.Enabled == nil || *r.config.Enabled
+}
+
+// active reports whether the current time is within ActiveFrom (inclusive) and ActiveUntil (exclusive), if set.
+func (r *rule) active() bool {
+	if r.activeFrom.IsZero() && r.activeUntil.IsZero() {
+		return true
+	}
+
+	now := nowFunc().UTC()
+	return (r.activeFrom.IsZero() || !now.Before(r.activeFrom)) && (r.activeUntil.IsZero() || now.Before(r.activeUntil))
+}
+
+// requestScheme returns the scheme the client used for the given request.
+// The X-Forwarded-Proto header takes precedence, as TLS may be terminated before this middleware.
+func requestScheme(req *http.Request) string {
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" {
+		return strings.ToLower(proto)
+	}
+	if req.URL.Scheme != "" {
+		return strings.ToLower(req.URL.Scheme)
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// appliesToMethod reports whether a request with the given method should be modified.
+// Without any configured methods only GET requests are modified, or POST requests for the form target.
+func (r *rule) appliesToMethod(method string) bool {
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	if len(r.config.ApplyToMethods) == 0 {
+		if r.config.Target == formTarget || r.config.Target == jsonTarget {
+			return method == http.MethodPost
+		}
+		return method == http.MethodGet
+	}
+
+	for _, allowed := range r.config.ApplyToMethods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasQueryCondition reports whether the rule has conditions which are evaluated against the query.
+func (r *rule) hasQueryCondition() bool {
+	return r.conditionValueRegexCompiled != nil || r.config.RequirePresentParam != ""
+}
+
+// queryConditionMet reports whether the given query fulfills the query conditions of this rule.
+// An absent condition param does not fulfill the condition.
+func (r *rule) queryConditionMet(qry url.Values) bool {
+	if r.config.RequirePresentParam != "" {
+		if _, ok := qry[r.config.RequirePresentParam]; !ok {
+			return false
+		}
+	}
+
+	if r.conditionValueRegexCompiled != nil && !anyMatch(qry[r.config.ConditionParam], r.conditionValueRegexCompiled) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,320 @@
+package traefik_plugin_parameters_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestCondition_Met(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.ConditionParam = "env"
+	cfg.ConditionValueRegex = "^prod$"
+	previous := "debug=1&env=prod"
+	expected := "env=prod"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestCondition_NotMet(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.ConditionParam = "env"
+	cfg.ConditionValueRegex = "^prod$"
+	previous := "debug=1&env=staging"
+	expected := "debug=1&env=staging"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestCondition_ParamAbsent(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.ConditionParam = "env"
+	cfg.ConditionValueRegex = ".*"
+	previous := "debug=1"
+	expected := "debug=1"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestErrorConditionIncomplete(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.ConditionParam = "env"
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+func TestCondition_RequirePresentParamPresent(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "cache"
+	cfg.RequirePresentParam = "nocache"
+	previous := "cache=1&nocache"
+	expected := "nocache="
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestCondition_RequirePresentParamAbsent(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "cache"
+	cfg.RequirePresentParam = "nocache"
+	previous := "cache=1&other=2"
+	expected := "cache=1&other=2"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestCondition_RequirePresentParamAndValueCondition(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "cache"
+	cfg.RequirePresentParam = "nocache"
+	cfg.ConditionParam = "env"
+	cfg.ConditionValueRegex = "^prod$"
+
+	assertQueryModification(t, cfg, "cache=1&nocache=1&env=prod", "env=prod&nocache=1")
+	assertQueryModification(t, cfg, "cache=1&env=prod", "cache=1&env=prod")
+	assertQueryModification(t, cfg, "cache=1&nocache=1&env=dev", "cache=1&env=dev&nocache=1")
+}
+
+func TestCondition_RuleDisabled(t *testing.T) {
+	disabled, enabled := false, true
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "delete", ParamName: "a", Enabled: &disabled},
+		{Type: "delete", ParamName: "b", Enabled: &enabled},
+		{Type: "delete", ParamName: "c"},
+	}
+	previous := "a=1&b=2&c=3&d=4"
+	expected := "a=1&d=4"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestCondition_DisabledRuleValidated(t *testing.T) {
+	disabled := false
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "delete", Enabled: &disabled},
+	}
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+func TestCondition_SchemeForwardedHTTPS(t *testing.T) {
+	assertSchemeCondition(t, "https", "token=1&a=2", "a=2")
+}
+
+func TestCondition_SchemeForwardedHTTP(t *testing.T) {
+	assertSchemeCondition(t, "http", "token=1&a=2", "a=2&token=1")
+}
+
+func TestCondition_SchemeFromURL(t *testing.T) {
+	assertSchemeCondition(t, "", "token=1&a=2", "a=2&token=1")
+}
+
+func assertSchemeCondition(t *testing.T, forwardedProto, previous, expected string) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "token"
+	cfg.SchemeRegex = "^https$"
+
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	if forwardedProto != "" {
+		req.Header.Set("X-Forwarded-Proto", forwardedProto)
+	}
+	req.URL.RawQuery = previous
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+}
+
+func TestCondition_ContentTypeMatching(t *testing.T) {
+	assertContentTypeCondition(t, "application/json; charset=utf-8", "a=1&b=2", "b=2")
+}
+
+func TestCondition_ContentTypeNotMatching(t *testing.T) {
+	assertContentTypeCondition(t, "application/x-www-form-urlencoded", "a=1&b=2", "a=1&b=2")
+}
+
+func TestCondition_ContentTypeAbsent(t *testing.T) {
+	assertContentTypeCondition(t, "", "a=1&b=2", "a=1&b=2")
+}
+
+func assertContentTypeCondition(t *testing.T, contentType, previous, expected string) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.ContentTypeRegex = "^application/json"
+
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.URL.RawQuery = previous
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+}
+
+func TestCondition_AcceptMatching(t *testing.T) {
+	assertAcceptCondition(t, "text/html,application/xhtml+xml;q=0.9", "a=1&format=html")
+}
+
+func TestCondition_AcceptNotMatching(t *testing.T) {
+	assertAcceptCondition(t, "application/json", "a=1")
+}
+
+func TestCondition_AcceptAbsent(t *testing.T) {
+	assertAcceptCondition(t, "", "a=1")
+}
+
+func TestCondition_AcceptPerRule(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "add-if-absent", ParamName: "limit", NewValue: "100", AcceptRegex: "application/json"},
+		{Type: "add-if-absent", ParamName: "limit", NewValue: "20", AcceptRegex: "text/html"},
+	}
+
+	for accept, expected := range map[string]string{"application/json": "limit=100", "text/html": "limit=20", "*/*": ""} {
+		handler, err, recorder, req := createReqAndRecorder(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", accept)
+		req.URL.RawQuery = ""
+		handler.ServeHTTP(recorder, req)
+
+		if req.URL.RawQuery != expected {
+			t.Errorf("Expected %q for Accept %s, got %q", expected, accept, req.URL.RawQuery)
+		}
+	}
+}
+
+func assertAcceptCondition(t *testing.T, accept, expected string) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "format"
+	cfg.NewValue = "html"
+	cfg.AcceptRegex = "(^|,)\\s*text/html"
+
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	req.URL.RawQuery = "a=1"
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+}
+
+func TestCondition_TimeWindow(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "promo"
+	cfg.NewValue = "sale"
+	cfg.ActiveFrom = "2024-11-29T00:00:00+01:00"
+	cfg.ActiveUntil = "2024-12-02T00:00:00Z"
+
+	testCases := []struct {
+		desc     string
+		now      time.Time
+		expected string
+	}{
+		{desc: "before", now: time.Date(2024, 11, 28, 22, 59, 59, 0, time.UTC), expected: "a=1"},
+		{desc: "start in other timezone", now: time.Date(2024, 11, 28, 23, 0, 0, 0, time.UTC), expected: "a=1&promo=sale"},
+		{desc: "within", now: time.Date(2024, 11, 30, 12, 0, 0, 0, time.FixedZone("PST", -8*60*60)), expected: "a=1&promo=sale"},
+		{desc: "end is exclusive", now: time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC), expected: "a=1"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			now := test.now
+			restore := traefik_plugin_parameters.SetNowFunc(func() time.Time { return now })
+			defer restore()
+
+			assertQueryModification(t, cfg, "a=1", test.expected)
+		})
+	}
+}
+
+func TestCondition_TimeWindowOpenEnded(t *testing.T) {
+	restore := traefik_plugin_parameters.SetNowFunc(func() time.Time { return time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC) })
+	defer restore()
+
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "legacy"
+	cfg.ActiveFrom = "2025-01-01T00:00:00Z"
+
+	assertQueryModification(t, cfg, "legacy=1&a=1", "a=1")
+
+	cfg.ActiveFrom = ""
+	cfg.ActiveUntil = "2025-01-01T00:00:00Z"
+	assertQueryModification(t, cfg, "legacy=1&a=1", "a=1&legacy=1")
+}
+
+func TestCondition_TimeWindowErrors(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		activeFrom  string
+		activeUntil string
+	}{
+		{desc: "invalid activeFrom", activeFrom: "2024-11-29"},
+		{desc: "invalid activeUntil", activeUntil: "tomorrow"},
+		{desc: "empty window", activeFrom: "2024-12-02T00:00:00Z", activeUntil: "2024-12-01T00:00:00Z"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.Type = "delete"
+			cfg.ParamName = "a"
+			cfg.ActiveFrom = test.activeFrom
+			cfg.ActiveUntil = test.activeUntil
+			_, err, _, _ := createReqAndRecorder(cfg)
+
+			if err == nil {
+				t.Error("expected error but err is nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package traefik_plugin_parameters
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextKey is the type of the keys under which the queries are stored in the request context
+type contextKey string
+
+const (
+	// OriginalQueryKey is the context key of the raw query before the rules were applied
+	OriginalQueryKey contextKey = "traefik-plugin-parameters-original-query"
+	// ModifiedQueryKey is the context key of the raw query after the rules were applied, which is forwarded
+	ModifiedQueryKey contextKey = "traefik-plugin-parameters-modified-query"
+)
+
+// OriginalQuery returns the raw query of the request before the rules were applied, as stored in the given context
+func OriginalQuery(ctx context.Context) (string, bool) {
+	query, ok := ctx.Value(OriginalQueryKey).(string)
+	return query, ok
+}
+
+// ModifiedQuery returns the raw query of the request after the rules were applied, as stored in the given context
+func ModifiedQuery(ctx context.Context) (string, bool) {
+	query, ok := ctx.Value(ModifiedQueryKey).(string)
+	return query, ok
+}
+
+// withQueries returns a shallow copy of the given request whose context carries the given original query
+// and the current query of the request, so later middlewares can read both
+func withQueries(req *http.Request, originalQuery string) *http.Request {
+	ctx := context.WithValue(req.Context(), OriginalQueryKey, originalQuery)
+	ctx = context.WithValue(ctx, ModifiedQueryKey, req.URL.RawQuery)
+	return req.WithContext(ctx)
+}
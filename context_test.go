@@ -0,0 +1,147 @@
+package traefik_plugin_parameters_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestContext_CarriesOriginalAndModifiedQuery(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+
+	var original, modified string
+	var originalOK, modifiedOK bool
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		original, originalOK = traefik_plugin_parameters.OriginalQuery(req.Context())
+		modified, modifiedOK = traefik_plugin_parameters.ModifiedQuery(req.Context())
+		if req.URL.RawQuery != modified {
+			t.Errorf("expected the forwarded query %q to equal the modified query %q", req.URL.RawQuery, modified)
+		}
+	})
+	handler := newHandler(t, cfg, next)
+
+	serve(handler, http.MethodGet, "http://localhost/?a=1&debug=true")
+
+	if !originalOK || original != "a=1&debug=true" {
+		t.Errorf("expected the original query a=1&debug=true in the context, got %q (present: %t)", original, originalOK)
+	}
+	if !modifiedOK || modified != "a=1" {
+		t.Errorf("expected the modified query a=1 in the context, got %q (present: %t)", modified, modifiedOK)
+	}
+}
+
+func TestContext_ExportedKeys(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "b"
+	cfg.NewValue = "2"
+
+	var original, modified interface{}
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		original = req.Context().Value(traefik_plugin_parameters.OriginalQueryKey)
+		modified = req.Context().Value(traefik_plugin_parameters.ModifiedQueryKey)
+	})
+	handler := newHandler(t, cfg, next)
+
+	serve(handler, http.MethodGet, "http://localhost/?a=1")
+
+	if original != "a=1" || modified != "a=1&b=2" {
+		t.Errorf("expected a=1 and a=1&b=2 in the context, got %v and %v", original, modified)
+	}
+}
+
+func TestContext_CancelledRequest(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	handler, queries := newContextHandler(t, cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/?a=1&debug=true", nil).WithContext(ctx)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(*queries) != 1 || (*queries)[0] != [2]string{"a=1&debug=true", "a=1&debug=true"} {
+		t.Errorf("expected the unmodified query as original and modified query, got %q", *queries)
+	}
+}
+
+func TestContext_NotSampled(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "debug"
+	cfg.SamplePercent = 1
+	handler, queries := newContextHandler(t, cfg)
+	handler.SetRandomSeed(1)
+
+	for i := 0; i < 10; i++ {
+		serve(handler, http.MethodGet, "http://localhost/?a=1&debug=true")
+	}
+
+	unmodified := 0
+	for _, query := range *queries {
+		if query[0] != "a=1&debug=true" {
+			t.Errorf("expected the original query a=1&debug=true, got %q", query[0])
+		}
+		if query[1] == "a=1&debug=true" {
+			unmodified++
+		}
+	}
+	if len(*queries) != 10 || unmodified == 0 {
+		t.Errorf("expected the queries of unsampled requests in the context, got %q", *queries)
+	}
+}
+
+func TestContext_RetriedRequest(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RetryOnStatus = []int{http.StatusNotFound}
+	cfg.RetryRules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "variant"}}
+
+	var queries [][2]string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		original, _ := traefik_plugin_parameters.OriginalQuery(req.Context())
+		modified, _ := traefik_plugin_parameters.ModifiedQuery(req.Context())
+		queries = append(queries, [2]string{original, modified})
+		if len(queries) == 1 {
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	})
+	handler := newHandler(t, cfg, next)
+	serve(handler, http.MethodGet, "http://localhost/?a=1&variant=beta")
+
+	if len(queries) != 2 || queries[1] != [2]string{"a=1&variant=beta", "a=1"} {
+		t.Errorf("expected the retried query as modified query, got %q", queries)
+	}
+}
+
+func TestContext_AbsentWithoutPlugin(t *testing.T) {
+	if _, ok := traefik_plugin_parameters.OriginalQuery(context.Background()); ok {
+		t.Error("expected no original query in an empty context")
+	}
+	if _, ok := traefik_plugin_parameters.ModifiedQuery(context.Background()); ok {
+		t.Error("expected no modified query in an empty context")
+	}
+}
+
+// newContextHandler creates a handler whose next handler records the original and modified query of every request,
+// a missing query is recorded as "<absent>"
+func newContextHandler(t *testing.T, cfg *traefik_plugin_parameters.Config) (*traefik_plugin_parameters.QueryModification, *[][2]string) {
+	var queries [][2]string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		original, ok := traefik_plugin_parameters.OriginalQuery(req.Context())
+		if !ok {
+			original = "<absent>"
+		}
+		modified, ok := traefik_plugin_parameters.ModifiedQuery(req.Context())
+		if !ok {
+			modified = "<absent>"
+		}
+		queries = append(queries, [2]string{original, modified})
+	})
+	return newHandler(t, cfg, next), &queries
+}
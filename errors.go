@@ -0,0 +1,59 @@
+package traefik_plugin_parameters
+
+import "errors"
+
+// Errors classifying invalid configurations returned by New and ValidateConfig, to be checked with errors.Is.
+// The returned errors keep their detailed messages, these values only identify the kind of failure.
+var (
+	// ErrInvalidType is returned if type is not set or unknown
+	ErrInvalidType = errors.New("invalid modification type")
+	// ErrInvalidTarget is returned if target or targets is unknown or not supported by the type or options of the rule
+	ErrInvalidTarget = errors.New("invalid target")
+	// ErrNoMatcher is returned if a rule lacks the param matchers its type requires
+	ErrNoMatcher = errors.New("no param matcher")
+	// ErrRegexCompile is returned if a regex of the configuration cannot be compiled, see also RegexError
+	ErrRegexCompile = errors.New("regex could not be compiled")
+	// ErrRegexTooLong is returned if a regex of the configuration exceeds maxRegexLength
+	ErrRegexTooLong = errors.New("regex too long")
+)
+
+// RegexError is returned if a regex of the configuration cannot be compiled.
+// It wraps the error of the regexp package and matches ErrRegexCompile.
+type RegexError struct {
+	// Field is the name of the config field holding the regex, e.g. paramNameRegex
+	Field string
+	Err   error
+}
+
+func (e *RegexError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the regexp package
+func (e *RegexError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the given target is ErrRegexCompile
+func (e *RegexError) Is(target error) bool {
+	return target == ErrRegexCompile
+}
+
+// configError is an error with its own message which is classified by one of the exported errors
+type configError struct {
+	kind    error
+	message string
+}
+
+// newConfigError creates an error with the given message matching the given kind with errors.Is
+func newConfigError(kind error, message string) error {
+	return &configError{kind: kind, message: message}
+}
+
+func (e *configError) Error() string {
+	return e.message
+}
+
+func (e *configError) Unwrap() error {
+	return e.kind
+}
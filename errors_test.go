@@ -0,0 +1,87 @@
+package traefik_plugin_parameters_test
+
+import (
+	"errors"
+	"regexp/syntax"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		desc     string
+		config   traefik_plugin_parameters.RuleConfig
+		expected error
+	}{
+		{desc: "empty type", config: traefik_plugin_parameters.RuleConfig{ParamName: "a"}, expected: traefik_plugin_parameters.ErrInvalidType},
+		{desc: "unknown type", config: traefik_plugin_parameters.RuleConfig{Type: "unknown", ParamName: "a"}, expected: traefik_plugin_parameters.ErrInvalidType},
+		{desc: "unknown target", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", Target: "cookie"}, expected: traefik_plugin_parameters.ErrInvalidTarget},
+		{desc: "unknown targets", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", Targets: []string{"query", "form"}}, expected: traefik_plugin_parameters.ErrInvalidTarget},
+		{desc: "copy-to-header on header", config: traefik_plugin_parameters.RuleConfig{Type: "copy-to-header", ParamName: "a", Target: "header"}, expected: traefik_plugin_parameters.ErrInvalidTarget},
+		{desc: "clear on form", config: traefik_plugin_parameters.RuleConfig{Type: "clear", Target: "form"}, expected: traefik_plugin_parameters.ErrInvalidTarget},
+		{desc: "matchRawName on header", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", Target: "header", MatchRawName: true}, expected: traefik_plugin_parameters.ErrInvalidTarget},
+		{desc: "matchRawValue on form", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamValueRegex: "a", Target: "form", MatchRawValue: true}, expected: traefik_plugin_parameters.ErrInvalidTarget},
+		{desc: "matchEmptyValue on header", config: traefik_plugin_parameters.RuleConfig{Type: "delete", Target: "header", MatchEmptyValue: true}, expected: traefik_plugin_parameters.ErrInvalidTarget},
+		{desc: "no matcher", config: traefik_plugin_parameters.RuleConfig{Type: "delete"}, expected: traefik_plugin_parameters.ErrNoMatcher},
+		{desc: "add without name", config: traefik_plugin_parameters.RuleConfig{Type: "add", NewValue: "b"}, expected: traefik_plugin_parameters.ErrNoMatcher},
+		{desc: "invalid regex", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamNameRegex: "("}, expected: traefik_plugin_parameters.ErrRegexCompile},
+		{desc: "over-length regex", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamValueRegex: strings.Repeat("a", 1025)}, expected: traefik_plugin_parameters.ErrRegexTooLong},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = test.config
+
+			err := traefik_plugin_parameters.ValidateConfig(cfg)
+			if !errors.Is(err, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestConfigErrors_KeepMessages(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+
+	err := traefik_plugin_parameters.ValidateConfig(cfg)
+	if err == nil || !strings.HasPrefix(err.Error(), "either paramNameRegex or paramName") {
+		t.Errorf("expected the detailed message, got %v", err)
+	}
+}
+
+func TestConfigErrors_WrappedInRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "a"}, {Type: "unknown", ParamName: "b"}}
+
+	err := traefik_plugin_parameters.ValidateConfig(cfg)
+	if !errors.Is(err, traefik_plugin_parameters.ErrInvalidType) {
+		t.Errorf("expected %v, got %v", traefik_plugin_parameters.ErrInvalidType, err)
+	}
+}
+
+func TestRegexError(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameRegex = "("
+
+	err := traefik_plugin_parameters.ValidateConfig(cfg)
+	var regexErr *traefik_plugin_parameters.RegexError
+	if !errors.As(err, &regexErr) {
+		t.Fatalf("expected a RegexError, got %v", err)
+	}
+	if regexErr.Field != "paramNameRegex" {
+		t.Errorf("expected field paramNameRegex, got %s", regexErr.Field)
+	}
+
+	var syntaxErr *syntax.Error
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("expected the error of the regexp package to be wrapped, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "paramNameRegex: error parsing regexp") {
+		t.Errorf("expected the detailed message, got %v", err)
+	}
+}
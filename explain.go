@@ -0,0 +1,75 @@
+package traefik_plugin_parameters
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// Change describes the change of a single param, header or field by a rule, as returned by Explain.
+// Absent params have nil values, so adding a param has nil OldValues and deleting a param has nil NewValues.
+type Change struct {
+	// Rule is the name of the rule, see RuleConfig.Name
+	Rule      string
+	Type      string
+	Key       string
+	OldValues []string
+	NewValues []string
+}
+
+// Explain returns the changes the rules would apply to the given request, in the order they would be applied,
+// e.g. to test a configuration against recorded traffic. Unlike Apply, all targets and conditions are evaluated.
+// The request is left untouched and the next handler is not called, even dry run mode is ignored.
+// Checks rejecting requests, like verifySignature or requireParam, and samplePercent are not evaluated.
+// Form and JSON bodies are taken from GetBody if set, otherwise at most maxBodyBytes of them are read
+// and put back in front of the rest of the body, GetBody and ContentLength are left untouched.
+func (q *QueryModification) Explain(req *http.Request) []Change {
+	explainReq := req.Clone(req.Context())
+	if isFormRequest(req) || isJSONRequest(req) {
+		body, err := q.explainBody(req, explainReq)
+		if err != nil {
+			return nil
+		}
+		explainReq.Body = body
+	}
+
+	var explained []auditModification
+	if _, err := q.modifyRequest(explainReq, &explained); err != nil {
+		return nil
+	}
+
+	changes := make([]Change, 0, len(explained))
+	for _, modification := range explained {
+		changes = append(changes, Change{
+			Rule:      modification.Rule,
+			Type:      string(modification.Type),
+			Key:       modification.Param,
+			OldValues: modification.OldValues,
+			NewValues: modification.NewValues,
+		})
+	}
+	return changes
+}
+
+// explainBody returns a body for the given clone of the given request with the same content.
+// Without GetBody, the body is read from the clone, so the request gets a body reading the same bytes again.
+func (q *QueryModification) explainBody(req, explainReq *http.Request) (io.ReadCloser, error) {
+	if req.GetBody != nil {
+		return req.GetBody()
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return req.Body, nil
+	}
+
+	var reader io.Reader = explainReq.Body
+	if q.config.MaxBodyBytes > 0 {
+		// one more byte is read to tell a body of exactly maxBodyBytes from a larger one
+		reader = io.LimitReader(reader, q.config.MaxBodyBytes+1)
+	}
+	body, err := io.ReadAll(reader)
+	req.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), req.Body), Closer: req.Body}
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(body)), nil
+}
@@ -0,0 +1,182 @@
+package traefik_plugin_parameters_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestExplain_MatchesAppliedChanges(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.LogLevel = "none"
+	cfg.AuditLog = true
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "modify", ParamName: "a", NewValue: "x", Name: "rewrite-a"},
+		{Type: "delete", ParamNameRegex: "^utm_"},
+		{Type: "add", ParamName: "source", NewValue: "proxy"},
+		{Type: "add", Target: "header", ParamName: "X-Modified", NewValue: "1"},
+		{Type: "delete", ParamName: "missing"},
+	}
+	handler, called := newExplainHandler(t, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/items?a=1&a=2&utm_source=x&utm_medium=y&b=3", nil)
+	changes := handler.Explain(req)
+
+	if *called {
+		t.Error("Expected the next handler not to be called")
+	}
+	if req.URL.RawQuery != "a=1&a=2&utm_source=x&utm_medium=y&b=3" || req.Header.Get("X-Modified") != "" {
+		t.Fatalf("Expected the request to be untouched, got %s %v", req.URL.RawQuery, req.Header)
+	}
+
+	expected := []traefik_plugin_parameters.Change{
+		{Rule: "rewrite-a", Type: "modify", Key: "a", OldValues: []string{"1", "2"}, NewValues: []string{"x", "x"}},
+		{Rule: "rules[1]", Type: "delete", Key: "utm_medium", OldValues: []string{"y"}},
+		{Rule: "rules[1]", Type: "delete", Key: "utm_source", OldValues: []string{"x"}},
+		{Rule: "rules[2]", Type: "add", Key: "source", NewValues: []string{"proxy"}},
+		{Rule: "rules[3]", Type: "add", Key: "X-Modified", NewValues: []string{"1"}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, changes)
+	}
+
+	// the audit log of the actual modification lists the same changes
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry auditEntry
+	if err := json.Unmarshal(logged.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected JSON, got %s: %v", logged.String(), err)
+	}
+	applied := make([]traefik_plugin_parameters.Change, 0, len(entry.Modifications))
+	for _, modification := range entry.Modifications {
+		applied = append(applied, traefik_plugin_parameters.Change{
+			Rule:      modification.Rule,
+			Type:      modification.Type,
+			Key:       modification.Param,
+			OldValues: modification.OldValues,
+			NewValues: modification.NewValues,
+		})
+	}
+	if !reflect.DeepEqual(changes, applied) {
+		t.Errorf("Expected the applied changes %+v to match %+v", applied, changes)
+	}
+}
+
+func TestExplain_IgnoresDryRun(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.DryRun = true
+	cfg.LogLevel = "none"
+	handler, _ := newExplainHandler(t, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost?a=1", nil)
+	changes := handler.Explain(req)
+
+	expected := []traefik_plugin_parameters.Change{{Rule: "rule", Type: "delete", Key: "a", OldValues: []string{"1"}}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, changes)
+	}
+}
+
+func TestExplain_NoChanges(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.PathRegex = "^/api/"
+	handler, _ := newExplainHandler(t, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/web?a=1", nil)
+	if changes := handler.Explain(req); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %+v", changes)
+	}
+}
+
+func TestExplain_FormBodyKept(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "form"
+	cfg.ParamName = "password"
+	handler, _ := newExplainHandler(t, cfg)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader("password=secret&user=john"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	changes := handler.Explain(req)
+
+	expected := []traefik_plugin_parameters.Change{{Rule: "rule", Type: "delete", Key: "password", OldValues: []string{"secret"}}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, changes)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "password=secret&user=john" {
+		t.Errorf("Expected the body to be kept, got %s", body)
+	}
+}
+
+func TestExplain_BodyFromGetBody(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "form"
+	cfg.ParamName = "password"
+	handler, _ := newExplainHandler(t, cfg)
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost", strings.NewReader("password=secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body := req.Body
+	changes := handler.Explain(req)
+
+	if len(changes) != 1 || req.Body != body || req.ContentLength != int64(len("password=secret")) {
+		t.Errorf("Expected a change and the body to be left untouched, got %+v", changes)
+	}
+}
+
+func TestExplain_RespectsMaxBodyBytes(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "form"
+	cfg.ParamName = "password"
+	cfg.MaxBodyBytes = 10
+	handler, _ := newExplainHandler(t, cfg)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader("password=secret&user=john"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	changes := handler.Explain(req)
+
+	if len(changes) != 0 || req.GetBody != nil || req.ContentLength != int64(len("password=secret&user=john")) {
+		t.Errorf("Expected no changes for a body exceeding maxBodyBytes, got %+v", changes)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "password=secret&user=john" {
+		t.Errorf("Expected the body to be kept, got %s", body)
+	}
+}
+
+func newExplainHandler(t *testing.T, cfg *traefik_plugin_parameters.Config) (*traefik_plugin_parameters.QueryModification, *bool) {
+	called := false
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		called = true
+	})
+	return newHandler(t, cfg, next), &called
+}
@@ -0,0 +1,26 @@
+package traefik_plugin_parameters
+
+import (
+	"math/rand"
+	"net/url"
+	"time"
+)
+
+// SetQueryEncoder replaces the encoding of modified queries, e.g. to simulate an encoding producing malformed queries.
+func (q *QueryModification) SetQueryEncoder(encode func(rawQuery string, qry url.Values) string) {
+	q.encode = func(rawQuery string, qry url.Values, _ map[string]string) string {
+		return encode(rawQuery, qry)
+	}
+}
+
+// SetRandomSeed seeds the random draws of samplePercent, so sampling becomes deterministic.
+func (q *QueryModification) SetRandomSeed(seed int64) {
+	q.random = rand.New(rand.NewSource(seed))
+}
+
+// SetNowFunc replaces the clock activeFrom and activeUntil are checked against and returns a function restoring it.
+func SetNowFunc(now func() time.Time) (restore func()) {
+	previous := nowFunc
+	nowFunc = now
+	return func() { nowFunc = previous }
+}
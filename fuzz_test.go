@@ -0,0 +1,39 @@
+//go:build go1.18
+// +build go1.18
+
+package traefik_plugin_parameters_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func FuzzServeHTTP(f *testing.F) {
+	for _, seed := range []string{"", "a=b", "a=%zz&b", "a=1;b=2", "a=b&a=c&d", "==&&=", "utm_source=x%20y+z"} {
+		f.Add(seed)
+	}
+
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "delete", ParamNameRegex: "^utm_"},
+		{Type: "modify", ParamValueRegex: "^(.*)$", NewValueRegex: "x-$1"},
+		{Type: "add", ParamName: "added", NewValue: "1"},
+	}
+
+	f.Fuzz(func(t *testing.T, rawQuery string) {
+		calls := 0
+		next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) { calls++ })
+		handler := newHandler(t, cfg, next)
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		req.URL.RawQuery = rawQuery
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if calls != 1 {
+			t.Errorf("Expected next to be called once, got %d calls", calls)
+		}
+	})
+}
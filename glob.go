@@ -0,0 +1,25 @@
+package traefik_plugin_parameters
+
+import (
+	"regexp"
+	"strings"
+)
+
+// globToRegex translates a shell-style glob into an anchored regex.
+// `*` matches any sequence of characters and `?` matches a single character, everything else matches literally.
+func globToRegex(glob string) string {
+	var sb strings.Builder
+	sb.WriteByte('^')
+	for _, c := range glob {
+		switch c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteByte('.')
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteByte('$')
+	return sb.String()
+}
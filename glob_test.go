@@ -0,0 +1,73 @@
+package traefik_plugin_parameters_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestGlob_DeleteByName(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameGlob = "utm_*"
+	previous := "utm_source=news&utm_medium=mail&utm=1&id=2"
+	expected := "id=2&utm=1"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestGlob_SingleCharacter(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameGlob = "a?"
+	previous := "a=1&ab=2&abc=3"
+	expected := "a=1&abc=3"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestGlob_MatchesLiterally(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameGlob = "a.b"
+	previous := "a.b=1&axb=2"
+	expected := "axb=2"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestGlob_ModifyByValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamValueGlob = "secret-*"
+	cfg.NewValue = "redacted"
+	previous := "a=secret-1&b=public&c=my-secret-2"
+	expected := "a=redacted&b=public&c=my-secret-2"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestGlob_ErrorCombinedWithRegex(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config traefik_plugin_parameters.RuleConfig
+	}{
+		{desc: "name", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamNameGlob: "utm_*", ParamNameRegex: "^utm_"}},
+		{desc: "value", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamValueGlob: "a*", ParamValueRegex: "^a"}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = test.config
+			next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+			_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+			if err == nil {
+				t.Error("expected error but err is nil")
+			}
+		})
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/dev-toolbox/traefik-plugin-parameters
 
 go 1.14
+
+require github.com/mitchellh/mapstructure v1.5.0
@@ -0,0 +1,95 @@
+package traefik_plugin_parameters
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// ModificationHook is consulted for every param a rule is about to change, e.g. to enforce policies on rewrites.
+// Returning false vetoes the change, the param keeps its old values.
+// Absent params have nil values, so adding a param has nil oldValues and deleting a param has nil newValues.
+type ModificationHook interface {
+	Allow(ctx context.Context, paramName string, oldValues, newValues []string) bool
+}
+
+// SetModificationHook sets the hook consulted before applying modifications.
+// It must be called before the first request is served, a nil hook allows all modifications.
+func (q *QueryModification) SetModificationHook(hook ModificationHook) {
+	q.hook = hook
+}
+
+// applyWithHook calls apply to modify the given params and reverts all changes vetoed by the modification hook.
+// It returns the names reported by apply, excluding the vetoed ones.
+func (q *QueryModification) applyWithHook(ctx context.Context, params map[string][]string, apply func() []string) []string {
+	if q.hook == nil {
+		return apply()
+	}
+
+	before := cloneParams(params)
+	changed := apply()
+
+	keys := make([]string, 0, len(params))
+	for key, values := range params {
+		if !equalValues(values, before[key]) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range before {
+		if _, ok := params[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	vetoed := make(map[string]bool)
+	for _, key := range keys {
+		if q.hook.Allow(ctx, key, before[key], params[key]) {
+			continue
+		}
+		vetoed[key] = true
+		if oldValues, ok := before[key]; ok {
+			params[key] = oldValues
+		} else {
+			delete(params, key)
+		}
+	}
+
+	allowed := make([]string, 0, len(changed))
+	for _, key := range changed {
+		if !vetoed[key] {
+			allowed = append(allowed, key)
+		}
+	}
+	return allowed
+}
+
+// copyToHeaderWithHook copies the params targeted by the given rule to the header and removes them with RemoveParam.
+// The hook is consulted for the header first, the params are only removed if the header was allowed
+// and the hook allows removing them as well. It returns the names of the copied params.
+func (q *QueryModification) copyToHeaderWithHook(ctx context.Context, r *rule, qry url.Values, state *requestState) []string {
+	var copied []string
+	allowed := q.applyWithHook(ctx, state.header, func() []string {
+		copied = r.copyToHeader(qry, state)
+		if len(copied) == 0 {
+			return nil
+		}
+		return []string{http.CanonicalHeaderKey(r.headerName())}
+	})
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	q.applyWithHook(ctx, qry, func() []string { return r.removeCopied(qry, copied) })
+	return copied
+}
+
+// cloneParams returns a deep copy of the given params
+func cloneParams(params map[string][]string) map[string][]string {
+	clone := make(map[string][]string, len(params))
+	for key, values := range params {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
@@ -0,0 +1,138 @@
+package traefik_plugin_parameters_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+type vetoHook struct {
+	vetoed string
+	calls  []string
+}
+
+func (h *vetoHook) Allow(_ context.Context, paramName string, oldValues, newValues []string) bool {
+	h.calls = append(h.calls, paramName)
+	return paramName != h.vetoed
+}
+
+func TestHook_VetoesParam(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameRegex = "^utm_"
+	hook := &vetoHook{vetoed: "utm_campaign"}
+	handler := newHandler(t, cfg, nil)
+	handler.SetModificationHook(hook)
+
+	_, req := serve(handler, http.MethodGet, "http://localhost?utm_source=a&utm_campaign=b&id=1")
+
+	if expected := "id=1&utm_campaign=b"; req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+	if expected := []string{"utm_campaign", "utm_source"}; !reflect.DeepEqual(hook.calls, expected) {
+		t.Errorf("Expected hook calls %v, got %v", expected, hook.calls)
+	}
+}
+
+func TestHook_ReceivesOldAndNewValues(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.NewValue = "new-$1"
+	var oldValues, newValues []string
+	hook := hookFunc(func(_ context.Context, _ string, o, n []string) bool {
+		oldValues, newValues = o, n
+		return true
+	})
+	handler := newHandler(t, cfg, nil)
+	handler.SetModificationHook(hook)
+
+	_, req := serve(handler, http.MethodGet, "http://localhost?a=1&a=2")
+
+	if expected := "a=new-1&a=new-2"; req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+	if !reflect.DeepEqual(oldValues, []string{"1", "2"}) || !reflect.DeepEqual(newValues, []string{"new-1", "new-2"}) {
+		t.Errorf("Unexpected values passed to hook: %v -> %v", oldValues, newValues)
+	}
+}
+
+func TestHook_VetoesAddedHeader(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.Target = "header"
+	cfg.ParamName = "X-Added"
+	cfg.NewValue = "1"
+	hook := &vetoHook{vetoed: "X-Added"}
+	handler := newHandler(t, cfg, nil)
+	handler.SetModificationHook(hook)
+
+	_, req := serve(handler, http.MethodGet, "http://localhost")
+
+	if _, ok := req.Header["X-Added"]; ok {
+		t.Error("Expected the vetoed header to be absent")
+	}
+}
+
+func TestHook_VetoesCopiedHeader(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "copy-to-header"
+	cfg.ParamName = "token"
+	cfg.HeaderName = "X-Token"
+	cfg.RemoveParam = true
+	hook := &vetoHook{vetoed: "X-Token"}
+	handler := newHandler(t, cfg, nil)
+	handler.SetModificationHook(hook)
+
+	_, req := serve(handler, http.MethodGet, "http://localhost?token=abc")
+
+	if _, ok := req.Header["X-Token"]; ok || req.URL.RawQuery != "token=abc" {
+		t.Errorf("Expected neither the header nor the removal, got %v and %q", req.Header, req.URL.RawQuery)
+	}
+}
+
+func TestHook_VetoesRemovingCopiedParam(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "copy-to-header"
+	cfg.ParamName = "token"
+	cfg.HeaderName = "X-Token"
+	cfg.RemoveParam = true
+	hook := &vetoHook{vetoed: "token"}
+	handler := newHandler(t, cfg, nil)
+	handler.SetModificationHook(hook)
+
+	_, req := serve(handler, http.MethodGet, "http://localhost?token=abc")
+
+	if req.Header.Get("X-Token") != "abc" || req.URL.RawQuery != "token=abc" {
+		t.Errorf("Expected the header without the removal, got %v and %q", req.Header, req.URL.RawQuery)
+	}
+}
+
+func TestHook_NotConsultedForPathTemplate(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "version"
+	cfg.PathTemplate = "/v$1${path}"
+	var consulted []string
+	hook := hookFunc(func(_ context.Context, paramName string, _, _ []string) bool {
+		consulted = append(consulted, paramName)
+		return false
+	})
+	handler := newHandler(t, cfg, nil)
+	handler.SetModificationHook(hook)
+
+	_, req := serve(handler, http.MethodGet, "http://localhost?version=2")
+
+	if req.URL.Path != "/v2" || req.URL.RawQuery != "version=2" || !reflect.DeepEqual(consulted, []string{"version"}) {
+		t.Errorf("Expected the path to be rewritten and only the param to be vetoed, got %q %q %q", req.URL.Path, req.URL.RawQuery, consulted)
+	}
+}
+
+type hookFunc func(ctx context.Context, paramName string, oldValues, newValues []string) bool
+
+func (f hookFunc) Allow(ctx context.Context, paramName string, oldValues, newValues []string) bool {
+	return f(ctx, paramName, oldValues, newValues)
+}
@@ -0,0 +1,115 @@
+package traefik_plugin_parameters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// errNoJSONObject is returned for JSON bodies which are no object, their fields cannot be addressed
+var errNoJSONObject = errors.New("body is no JSON object")
+
+// isJSONRequest reports whether the request carries a JSON body, including structured syntax suffixes like application/problem+json
+func isJSONRequest(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// readJSONBody reads and parses the JSON body of the given request, reading at most maxBytes if positive.
+// Like readFormBody, the body is restored afterwards. Numbers are kept as json.Number, so they are encoded unchanged.
+func readJSONBody(req *http.Request, maxBytes int64) (map[string]interface{}, []byte, error) {
+	body, err := readBody(req, maxBytes)
+	if err != nil || len(body) == 0 {
+		return nil, body, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, body, err
+	}
+	if decoder.More() {
+		return nil, body, errors.New("body contains more than one JSON value")
+	}
+
+	object, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, body, errNoJSONObject
+	}
+	return object, body, nil
+}
+
+// encodeJSON encodes the given document without escaping HTML characters, so unchanged strings keep their form
+func encodeJSON(doc map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// modifyJSON applies the modification of this rule to the field of the given document addressed by the dot-path in paramName,
+// e.g. user.email. Fields within arrays cannot be addressed, missing fields are left alone.
+// Only string fields are modified and matched by value matchers, delete removes fields of any type without value matchers.
+// allow is consulted before the field is changed, like the modification hook for params.
+// It returns the path if the field was changed, along with its values before and after the change.
+func (r *rule) modifyJSON(doc map[string]interface{}, state *requestState, allow func(path string, oldValues, newValues []string) bool) ([]string, map[string][]string, map[string][]string) {
+	path := r.config.ParamName
+	segments := strings.Split(path, ".")
+	parent := doc
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := parent[segment].(map[string]interface{})
+		if !ok {
+			return nil, nil, nil
+		}
+		parent = child
+	}
+
+	field := segments[len(segments)-1]
+	value, ok := parent[field]
+	if !ok {
+		return nil, nil, nil
+	}
+
+	str, isString := value.(string)
+	var oldValues, newValues []string
+	if isString {
+		oldValues = []string{str}
+	}
+	switch r.config.Type {
+	case deleteType:
+		if r.hasValueMatcher() {
+			if !isString {
+				return nil, nil, nil
+			}
+			if _, deleted := r.deleteValues(path, oldValues, state); len(deleted) == 0 {
+				return nil, nil, nil
+			}
+		}
+	case modifyType:
+		if !isString {
+			r.logger.Debugf("msg=\"json field is no string, leaving it unchanged\" path=%q", path)
+			return nil, nil, nil
+		}
+		newValues = r.modifyValues(path, oldValues, state)
+		if equalValues(oldValues, newValues) {
+			return nil, nil, nil
+		}
+	}
+
+	if !allow(path, oldValues, newValues) {
+		return nil, nil, nil
+	}
+	if len(newValues) == 0 {
+		delete(parent, field)
+	} else {
+		parent[field] = newValues[0]
+	}
+	return []string{path}, map[string][]string{path: oldValues}, map[string][]string{path: newValues}
+}
@@ -0,0 +1,148 @@
+package traefik_plugin_parameters_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestJSON_ModifyNested(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.Target = "json"
+	cfg.ParamName = "user.email"
+	cfg.Transform = "sha256"
+
+	body, contentLength := serveForm(t, cfg, "application/json", `{"user":{"email":"a@b.c","age":42},"id":1}`)
+
+	hash := sha256.Sum256([]byte("a@b.c"))
+	expected := `{"id":1,"user":{"age":42,"email":"` + hex.EncodeToString(hash[:]) + `"}}`
+	if body != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+	if contentLength != int64(len(body)) {
+		t.Errorf("Expected content length %d, got %d", len(body), contentLength)
+	}
+}
+
+func TestJSON_ModifyNewValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.Target = "json"
+	cfg.ParamName = "user.name"
+	cfg.NewValue = "<censored>"
+
+	body, _ := serveForm(t, cfg, "application/json", `{"user":{"name":"john"}}`)
+
+	if body != `{"user":{"name":"<censored>"}}` {
+		t.Errorf("Expected %s, got %s", `{"user":{"name":"<censored>"}}`, body)
+	}
+}
+
+func TestJSON_DeleteNested(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "json"
+	cfg.ParamName = "credentials.password"
+
+	body, contentLength := serveForm(t, cfg, "application/json", `{"credentials":{"password":{"plain":"x"},"user":"john"}}`)
+
+	if body != `{"credentials":{"user":"john"}}` {
+		t.Errorf("Expected %s, got %s", `{"credentials":{"user":"john"}}`, body)
+	}
+	if contentLength != int64(len(body)) {
+		t.Errorf("Expected content length %d, got %d", len(body), contentLength)
+	}
+}
+
+func TestJSON_DeleteMatchingValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "json"
+	cfg.ParamName = "token"
+	cfg.ParamValueRegex = "^secret-"
+
+	body, _ := serveForm(t, cfg, "application/json", `{"token":"public"}`)
+	if body != `{"token":"public"}` {
+		t.Errorf("Expected the body unchanged, got %s", body)
+	}
+
+	body, _ = serveForm(t, cfg, "application/json", `{"token":"secret-1"}`)
+	if body != `{}` {
+		t.Errorf("Expected %s, got %s", `{}`, body)
+	}
+}
+
+func TestJSON_Unchanged(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.Target = "json"
+	cfg.ParamName = "user.email"
+	cfg.NewValue = "censored"
+
+	testCases := []struct {
+		desc        string
+		contentType string
+		body        string
+	}{
+		{desc: "missing path", contentType: "application/json", body: `{"user":{"name":"john"}}`},
+		{desc: "intermediate no object", contentType: "application/json", body: `{"user":"john"}`},
+		{desc: "no string", contentType: "application/json", body: `{"user":{"email":12345678901234567890}}`},
+		{desc: "array body", contentType: "application/json", body: `[{"user":{"email":"a@b.c"}}]`},
+		{desc: "malformed body", contentType: "application/json", body: `{"user":`},
+		{desc: "other content type", contentType: "text/plain", body: `{"user":{"email":"a@b.c"}}`},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			body, contentLength := serveForm(t, cfg, test.contentType, test.body)
+
+			if body != test.body {
+				t.Errorf("Expected %s, got %s", test.body, body)
+			}
+			if contentLength != int64(len(test.body)) {
+				t.Errorf("Expected content length %d, got %d", len(test.body), contentLength)
+			}
+		})
+	}
+}
+
+func TestJSON_SuffixContentType(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "json"
+	cfg.ParamName = "detail"
+
+	body, _ := serveForm(t, cfg, "application/problem+json; charset=utf-8", `{"detail":"x","title":"y"}`)
+
+	if body != `{"title":"y"}` {
+		t.Errorf("Expected %s, got %s", `{"title":"y"}`, body)
+	}
+}
+
+func TestJSON_Errors(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		config        traefik_plugin_parameters.RuleConfig
+		expectedError string
+	}{
+		{desc: "add", config: traefik_plugin_parameters.RuleConfig{Type: "add", Target: "json", ParamName: "a", NewValue: "b"}, expectedError: "json target can only be used with type modify or delete"},
+		{desc: "regex", config: traefik_plugin_parameters.RuleConfig{Type: "delete", Target: "json", ParamNameRegex: "^a"}, expectedError: "json target requires the path"},
+		{desc: "value only", config: traefik_plugin_parameters.RuleConfig{Type: "delete", Target: "json", ParamValueRegex: "^a"}, expectedError: "json target requires the path"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = test.config
+
+			err := traefik_plugin_parameters.ValidateConfig(cfg)
+			if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("expected error containing %q, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
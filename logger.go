@@ -0,0 +1,67 @@
+package traefik_plugin_parameters
+
+import (
+	"fmt"
+	"log"
+)
+
+type logLevel string
+
+const (
+	noneLogLevel  logLevel = "none"
+	warnLogLevel  logLevel = "warn"
+	debugLogLevel logLevel = "debug"
+)
+
+// logger writes the messages of a plugin instance up to the configured level using the standard logger,
+// so the output can be redirected with log.SetOutput.
+type logger struct {
+	level logLevel
+	name  string
+	// rule is the name of the rule the messages are about, it is empty for messages about the whole plugin
+	rule string
+}
+
+// newLogger creates a logger for the plugin instance with the given name, defaulting to the warn level
+func newLogger(level logLevel, name string) *logger {
+	if level == "" {
+		level = warnLogLevel
+	}
+	return &logger{level: level, name: name}
+}
+
+// forRule returns a copy of the logger adding the given rule name to all messages
+func (l *logger) forRule(rule string) *logger {
+	return &logger{level: l.level, name: l.name, rule: rule}
+}
+
+// Warnf logs a message unless logging is disabled
+func (l *logger) Warnf(format string, args ...interface{}) {
+	if l.level == warnLogLevel || l.level == debugLogLevel {
+		l.printf(warnLogLevel, format, args...)
+	}
+}
+
+// Debugf logs a message if the debug level is enabled
+func (l *logger) Debugf(format string, args ...interface{}) {
+	if l.level == debugLogLevel {
+		l.printf(debugLogLevel, format, args...)
+	}
+}
+
+func (l *logger) printf(level logLevel, format string, args ...interface{}) {
+	if l.rule != "" {
+		log.Printf("[Plugin Query Modification] level=%s plugin=%q %s rule=%q", level, l.name, fmt.Sprintf(format, args...), l.rule)
+		return
+	}
+	log.Printf("[Plugin Query Modification] level=%s plugin=%q %s", level, l.name, fmt.Sprintf(format, args...))
+}
+
+func (l logLevel) isValid() bool {
+	switch l {
+	case noneLogLevel, warnLogLevel, debugLogLevel, "":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,131 @@
+package traefik_plugin_parameters_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestLogLevel_None(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.ParamNameRegex = "^a$"
+	cfg.Transform = "base64decode"
+	cfg.LogLevel = "none"
+
+	logged := serveAndCaptureLog(t, cfg, "a=not-base64!")
+
+	if logged != "" {
+		t.Errorf("Expected no log output, got %s", logged)
+	}
+}
+
+func TestLogLevel_WarnByDefault(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.ParamNameRegex = "^a$"
+	cfg.NewValue = "b"
+
+	logged := serveAndCaptureLog(t, cfg, "a=c")
+
+	if !strings.Contains(logged, "level=warn") || !strings.Contains(logged, "discouraged") {
+		t.Errorf("Expected warning, got %s", logged)
+	}
+	if strings.Contains(logged, "level=debug") {
+		t.Errorf("Expected no debug output, got %s", logged)
+	}
+}
+
+func TestLogLevel_Debug(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.NewValue = "b"
+	cfg.LogLevel = "debug"
+
+	logged := serveAndCaptureLog(t, cfg, "a=c")
+
+	if !strings.Contains(logged, `level=debug plugin="query-modification-plugin" msg="applied modification" type=modify`) {
+		t.Errorf("Expected debug output, got %s", logged)
+	}
+}
+
+func TestLogLevel_RuleName(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.LogLevel = "debug"
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "modify", ParamName: "a", NewValue: "b", Name: "rewrite-a"},
+		{Type: "delete", ParamName: "c"},
+	}
+
+	logged := serveAndCaptureLog(t, cfg, "a=1&c=2")
+
+	if !strings.Contains(logged, `msg="applied modification" type=modify target=query params=["a"] rule="rewrite-a"`) {
+		t.Errorf("Expected the configured rule name, got %s", logged)
+	}
+	if !strings.Contains(logged, `msg="applied modification" type=delete target=query params=["c"] rule="rules[1]"`) {
+		t.Errorf("Expected the default rule name, got %s", logged)
+	}
+}
+
+func TestErrorInvalidLogLevel(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.LogLevel = "verbose"
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+// serveAndCaptureLog creates the handler and serves a request with the given query, returning the log output of both.
+func serveAndCaptureLog(t *testing.T, cfg *traefik_plugin_parameters.Config, rawQuery string) string {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	handler := newHandler(t, cfg, next)
+
+	serve(handler, http.MethodGet, "http://localhost?"+rawQuery)
+
+	return logged.String()
+}
+
+func TestLogLevel_DebugDeletedValues(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameRegex = "^(a|b)$"
+	cfg.LogLevel = "debug"
+
+	logged := serveAndCaptureLog(t, cfg, "b=3&a=1&a=2&c=4")
+
+	if !strings.Contains(logged, `msg="deleted values" target=query removed=["a=1" "a=2" "b=3"]`) {
+		t.Errorf("Expected deleted values, got %s", logged)
+	}
+}
+
+func TestLogLevel_DebugDeletedMatchingValuesOnly(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "tag"
+	cfg.ParamValueRegex = "^b$"
+	cfg.LogLevel = "debug"
+
+	logged := serveAndCaptureLog(t, cfg, "tag=a&tag=b&tag=c")
+
+	if !strings.Contains(logged, `msg="deleted values" target=query removed=["tag=b"]`) {
+		t.Errorf("Expected deleted values, got %s", logged)
+	}
+}
@@ -0,0 +1,64 @@
+package traefik_plugin_parameters_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+// decodeConfig decodes the given plugin configuration the way Traefik does
+func decodeConfig(t *testing.T, raw map[string]interface{}) *traefik_plugin_parameters.Config {
+	t.Helper()
+
+	cfg := traefik_plugin_parameters.CreateConfig()
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToSliceHookFunc(","),
+		WeaklyTypedInput: true,
+		Result:           cfg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoder.Decode(raw); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestMapstructure_SingleRule(t *testing.T) {
+	cfg := decodeConfig(t, map[string]interface{}{"type": "add", "paramName": "a", "newValue": "1", "dryRun": false})
+
+	_, req := serve(newHandler(t, cfg, nil), http.MethodGet, "http://localhost?b=2")
+
+	if req.URL.RawQuery != "a=1&b=2" {
+		t.Errorf("Expected a=1&b=2, got %s", req.URL.RawQuery)
+	}
+}
+
+func TestMapstructure_TestData(t *testing.T) {
+	cfg := decodeConfig(t, map[string]interface{}{"Type": "modify", "ParamName": "param", "NewValue": "newVal"})
+
+	_, req := serve(newHandler(t, cfg, nil), http.MethodGet, "http://localhost?param=old")
+
+	if req.URL.RawQuery != "param=newVal" {
+		t.Errorf("Expected param=newVal, got %s", req.URL.RawQuery)
+	}
+}
+
+func TestMapstructure_Rules(t *testing.T) {
+	cfg := decodeConfig(t, map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"type": "delete", "paramNameRegex": "^utm_"},
+			map[string]interface{}{"type": "add", "paramName": "a", "newValue": "1"},
+		},
+	})
+
+	_, req := serve(newHandler(t, cfg, nil), http.MethodGet, "http://localhost?utm_source=x&b=2")
+
+	if req.URL.RawQuery != "a=1&b=2" {
+		t.Errorf("Expected a=1&b=2, got %s", req.URL.RawQuery)
+	}
+}
@@ -0,0 +1,87 @@
+package traefik_plugin_parameters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// Matchers holds precompiled regexes used by a rule instead of compiling paramNameRegex and paramValueRegex,
+// e.g. when embedding the plugin into other Go programs. Unset fields leave the rule unchanged.
+type Matchers struct {
+	ParamNameRegex  *regexp.Regexp
+	ParamValueRegex *regexp.Regexp
+}
+
+// NewWithMatchers creates a new instance of this plugin like New, using the given precompiled matchers
+// for the inline rules with the given names, see RuleConfig.Name for the names of unnamed rules.
+// The rules must not configure the regexes replaced by the matchers, which are used as given,
+// so the names of headers are only matched case-insensitively if the regex says so.
+// The given configuration is left untouched.
+func NewWithMatchers(ctx context.Context, next http.Handler, config *Config, name string, matchers map[string]Matchers) (*QueryModification, error) {
+	prepared, err := withMatcherSources(config, matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	handler, err := New(ctx, next, prepared, name)
+	if err != nil {
+		return nil, err
+	}
+
+	q := handler.(*QueryModification)
+	for _, r := range q.rules {
+		m, ok := matchers[r.name]
+		if !ok {
+			continue
+		}
+		if m.ParamNameRegex != nil {
+			r.paramNameRegexCompiled = m.ParamNameRegex
+		}
+		if m.ParamValueRegex != nil {
+			r.paramValueRegexCompiled = m.ParamValueRegex
+		}
+	}
+	return q, nil
+}
+
+// withMatcherSources returns a copy of the given configuration whose rules named in matchers carry the sources
+// of the matchers as regexes, so the rules are validated as if the regexes were configured
+func withMatcherSources(config *Config, matchers map[string]Matchers) (*Config, error) {
+	prepared := *config
+	prepared.Rules = append([]RuleConfig(nil), config.Rules...)
+
+	ruleConfigs := map[string]*RuleConfig{ruleName(&prepared.RuleConfig, "rule"): &prepared.RuleConfig}
+	for i := range prepared.Rules {
+		ruleConfigs[ruleName(&prepared.Rules[i], fmt.Sprintf("rules[%d]", i))] = &prepared.Rules[i]
+	}
+
+	for name, m := range matchers {
+		ruleConfig, ok := ruleConfigs[name]
+		if !ok {
+			return nil, fmt.Errorf("matchers: unknown rule %q", name)
+		}
+		if m.ParamNameRegex != nil {
+			if ruleConfig.ParamNameRegex != "" {
+				return nil, fmt.Errorf("matchers: rule %q configures paramNameRegex as well", name)
+			}
+			ruleConfig.ParamNameRegex = m.ParamNameRegex.String()
+		}
+		if m.ParamValueRegex != nil {
+			if ruleConfig.ParamValueRegex != "" {
+				return nil, fmt.Errorf("matchers: rule %q configures paramValueRegex as well", name)
+			}
+			ruleConfig.ParamValueRegex = m.ParamValueRegex.String()
+		}
+	}
+	return &prepared, nil
+}
+
+// ruleName returns the name of the given rule, or the given default name based on its position if it is unnamed
+func ruleName(config *RuleConfig, defaultName string) string {
+	if config.Name != "" {
+		return config.Name
+	}
+	return defaultName
+}
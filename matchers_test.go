@@ -0,0 +1,101 @@
+package traefik_plugin_parameters_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestNewWithMatchers_TopLevelRule(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+
+	var forwarded string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		forwarded = req.URL.RawQuery
+	})
+	handler, err := traefik_plugin_parameters.NewWithMatchers(context.Background(), next, cfg, "query-modification-plugin",
+		map[string]traefik_plugin_parameters.Matchers{"rule": {ParamNameRegex: regexp.MustCompile("^utm_")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve(handler, http.MethodGet, "http://localhost/?utm_source=x&id=1")
+
+	if forwarded != "id=1" {
+		t.Errorf("expected id=1, got %s", forwarded)
+	}
+}
+
+func TestNewWithMatchers_NamedRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "modify", Name: "mask", NewValue: "***"},
+		{Type: "delete", ParamName: "debug"},
+	}
+
+	handler, err := traefik_plugin_parameters.NewWithMatchers(context.Background(), nil, cfg, "query-modification-plugin",
+		map[string]traefik_plugin_parameters.Matchers{
+			"mask":     {ParamValueRegex: regexp.MustCompile(`^\d{16}$`)},
+			"rules[1]": {ParamValueRegex: regexp.MustCompile("^true$")},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := handler.Apply(url.Values{"card": {"1234567812345678"}, "id": {"1"}, "debug": {"true"}})
+	expected := url.Values{"card": {"***"}, "id": {"1"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+
+	if cfg.Rules[0].ParamValueRegex != "" {
+		t.Errorf("expected the given config to be untouched, got paramValueRegex %q", cfg.Rules[0].ParamValueRegex)
+	}
+}
+
+func TestNewWithMatchers_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      traefik_plugin_parameters.RuleConfig
+		matchers map[string]traefik_plugin_parameters.Matchers
+		errMsg   string
+	}{
+		{
+			name:     "unknown rule",
+			cfg:      traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a"},
+			matchers: map[string]traefik_plugin_parameters.Matchers{"other": {ParamNameRegex: regexp.MustCompile("a")}},
+			errMsg:   `matchers: unknown rule "other"`,
+		},
+		{
+			name:     "configured regex",
+			cfg:      traefik_plugin_parameters.RuleConfig{Type: "delete", ParamNameRegex: "a"},
+			matchers: map[string]traefik_plugin_parameters.Matchers{"rule": {ParamNameRegex: regexp.MustCompile("b")}},
+			errMsg:   `matchers: rule "rule" configures paramNameRegex as well`,
+		},
+		{
+			name:     "invalid rule",
+			cfg:      traefik_plugin_parameters.RuleConfig{Type: "delete", ParamValueGlob: "a*"},
+			matchers: map[string]traefik_plugin_parameters.Matchers{"rule": {ParamValueRegex: regexp.MustCompile("b")}},
+			errMsg:   "paramValueGlob and paramValueRegex cannot be used together",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = tt.cfg
+
+			_, err := traefik_plugin_parameters.NewWithMatchers(context.Background(), nil, cfg, "query-modification-plugin", tt.matchers)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
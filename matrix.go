@@ -0,0 +1,84 @@
+package traefik_plugin_parameters
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// splitMatrix splits the matrix params off the last segment of the given escaped path,
+// e.g. /res;a=1;b=2 into /res and a=1, b=2, and returns the names in the order of their first occurrence.
+// Only unescaped ";" and "=" separate the params, names and values are unescaped. Params without "=" get an empty value.
+func splitMatrix(escapedPath string) (string, map[string][]string, []string) {
+	params := make(map[string][]string)
+	segmentStart := strings.LastIndex(escapedPath, "/") + 1
+	i := strings.IndexByte(escapedPath[segmentStart:], ';')
+	if i < 0 {
+		return escapedPath, params, nil
+	}
+
+	base := escapedPath[:segmentStart+i]
+	var order []string
+	for _, pair := range strings.Split(escapedPath[segmentStart+i+1:], ";") {
+		if pair == "" {
+			continue
+		}
+		key, value := pair, ""
+		if j := strings.IndexByte(pair, '='); j >= 0 {
+			key, value = pair[:j], pair[j+1:]
+		}
+		key, value = unescapeMatrix(key), unescapeMatrix(value)
+		if _, ok := params[key]; !ok {
+			order = append(order, key)
+		}
+		params[key] = append(params[key], value)
+	}
+	return base, params, order
+}
+
+// unescapeMatrix unescapes the given name or value of a matrix param, invalid escapes are kept as they are.
+func unescapeMatrix(s string) string {
+	unescaped, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+	return unescaped
+}
+
+// joinMatrix appends the given matrix params to the given escaped path.
+// The params are appended in the given order, params not contained in it follow sorted by name.
+// Names and values are escaped, it fails for empty names.
+func joinMatrix(base string, params map[string][]string, order []string) (string, error) {
+	keys := make([]string, 0, len(params))
+	ordered := make(map[string]bool, len(order))
+	for _, key := range order {
+		ordered[key] = true
+		if _, ok := params[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	var added []string
+	for key := range params {
+		if !ordered[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	keys = append(keys, added...)
+
+	var b strings.Builder
+	b.WriteString(base)
+	for _, key := range keys {
+		if key == "" {
+			return "", fmt.Errorf("invalid matrix param name %q", key)
+		}
+		for _, value := range params[key] {
+			b.WriteByte(';')
+			b.WriteString(strings.ReplaceAll(url.PathEscape(key), "=", "%3D"))
+			b.WriteByte('=')
+			b.WriteString(url.PathEscape(value))
+		}
+	}
+	return b.String(), nil
+}
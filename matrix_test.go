@@ -0,0 +1,93 @@
+package traefik_plugin_parameters_test
+
+import (
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestMatrix_Add(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.Target = "matrix"
+	cfg.ParamName = "c"
+	cfg.NewValue = "3"
+
+	assertPathRewrite(t, cfg, "/res;a=1;b=2", "q=1", "/res;a=1;b=2;c=3", "q=1", "/res;a=1;b=2;c=3?q=1")
+}
+
+func TestMatrix_AddWithoutParams(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.Target = "matrix"
+	cfg.ParamName = "a"
+	cfg.NewValue = "1"
+
+	assertPathRewrite(t, cfg, "/api/res", "", "/api/res;a=1", "", "/api/res;a=1")
+}
+
+func TestMatrix_Delete(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "matrix"
+	cfg.ParamName = "a"
+
+	assertPathRewrite(t, cfg, "/res;a=1;b=2;a=3", "a=1", "/res;b=2", "a=1", "/res;b=2?a=1")
+}
+
+func TestMatrix_OnlyLastSegment(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.Target = "matrix"
+	cfg.ParamName = "a"
+	cfg.NewValue = "x"
+
+	assertPathRewrite(t, cfg, "/dir;a=1/res;a=2", "", "/dir;a=1/res;a=x", "", "/dir;a=1/res;a=x")
+}
+
+func TestMatrix_NoMatch(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "matrix"
+	cfg.ParamName = "c"
+
+	assertPathRewrite(t, cfg, "/res;b=2;a=1", "", "/res;b=2;a=1", "", "")
+}
+
+func TestMatrix_EscapesSeparatorInValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.Target = "matrix"
+	cfg.ParamName = "a"
+	cfg.NewValue = "1;admin=true/x"
+
+	assertPathRewrite(t, cfg, "/res", "", "/res;a=1%3Badmin=true%2Fx", "", "/res;a=1%3Badmin=true%2Fx")
+}
+
+func TestMatrix_KeepsEscapedSeparators(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "matrix"
+	cfg.ParamName = "x"
+
+	assertPathRewrite(t, cfg, "/a%3Bb;x=1", "", "/a%3Bb", "", "/a%3Bb")
+}
+
+func TestMatrix_UnescapesValues(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.Target = "matrix"
+	cfg.ParamName = "a=b"
+	cfg.NewValue = "[$1]"
+
+	assertPathRewrite(t, cfg, "/res;a%3Db=1%3B2", "", "/res;a%3Db=%5B1%3B2%5D", "", "/res;a%3Db=%5B1%3B2%5D")
+}
+
+func TestMatrix_KeepsOrder(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "matrix"
+	cfg.ParamName = "x"
+
+	assertPathRewrite(t, cfg, "/res;b=2;x=1;a=3", "", "/res;b=2;a=3", "", "/res;b=2;a=3")
+}
@@ -0,0 +1,63 @@
+package traefik_plugin_parameters
+
+// MetricsSink receives a notification for every param changed by the plugin,
+// e.g. to increment a counter labeled with the modification type and the param name.
+type MetricsSink interface {
+	Inc(modificationType, paramName string)
+}
+
+// RuleMetricsSink is a MetricsSink which additionally receives the name of the rule changing the param,
+// e.g. to label a counter with it. If the sink set by SetMetricsSink implements it, IncRule is called instead of Inc.
+type RuleMetricsSink interface {
+	MetricsSink
+	IncRule(ruleName, modificationType, paramName string)
+}
+
+// RuleOutcomeSink is a MetricsSink which additionally counts the outcome of each rule per request.
+// If the sink set by SetMetricsSink implements it, IncMatched is called for every request fulfilling
+// the conditions of a rule, followed by IncApplied if the rule changed a param or IncSkipped otherwise,
+// e.g. to detect rules which never change anything.
+type RuleOutcomeSink interface {
+	MetricsSink
+	IncMatched(ruleName string)
+	IncApplied(ruleName string)
+	IncSkipped(ruleName string)
+}
+
+// noopMetricsSink is the default sink, discarding all notifications
+type noopMetricsSink struct{}
+
+func (noopMetricsSink) Inc(string, string) {}
+
+// SetMetricsSink sets the sink notified about applied modifications.
+// It must be called before the first request is served, a nil sink disables the notifications.
+func (q *QueryModification) SetMetricsSink(sink MetricsSink) {
+	if sink == nil {
+		sink = noopMetricsSink{}
+	}
+	q.metrics = sink
+}
+
+// incMetrics notifies the metrics sink about the given param changed by the given rule
+func (q *QueryModification) incMetrics(r *rule, paramName string) {
+	if sink, ok := q.metrics.(RuleMetricsSink); ok {
+		sink.IncRule(r.name, string(r.config.Type), paramName)
+		return
+	}
+	q.metrics.Inc(string(r.config.Type), paramName)
+}
+
+// countOutcome notifies the metrics sink whether the given rule, whose conditions were fulfilled, changed a param
+func (q *QueryModification) countOutcome(r *rule, changed []string) {
+	sink, ok := q.metrics.(RuleOutcomeSink)
+	if !ok {
+		return
+	}
+
+	sink.IncMatched(r.name)
+	if len(changed) > 0 {
+		sink.IncApplied(r.name)
+	} else {
+		sink.IncSkipped(r.name)
+	}
+}
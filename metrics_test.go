@@ -0,0 +1,139 @@
+package traefik_plugin_parameters_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+type fakeMetricsSink struct {
+	counts map[string]int
+}
+
+func (f *fakeMetricsSink) Inc(modificationType, paramName string) {
+	f.counts[modificationType+":"+paramName]++
+}
+
+func TestMetrics_CountsAppliedModifications(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "add", ParamName: "added", NewValue: "1"},
+		{Type: "delete", ParamNameRegex: "^utm_"},
+		{Type: "modify", ParamName: "a", NewValue: "c"},
+		{Type: "modify", ParamName: "unchanged", NewValue: "$1"},
+		{Type: "add-or-replace", ParamName: "b", NewValue: "2"},
+	}
+	sink := &fakeMetricsSink{counts: map[string]int{}}
+	handler := newHandler(t, cfg, nil)
+	handler.SetMetricsSink(sink)
+
+	for i := 0; i < 2; i++ {
+		serve(handler, http.MethodGet, "http://localhost?a=b&b=1&utm_source=x&utm_medium=y&unchanged=1")
+	}
+
+	expected := map[string]int{
+		"add:added":         2,
+		"delete:utm_source": 2,
+		"delete:utm_medium": 2,
+		"modify:a":          2,
+		"add-or-replace:b":  2,
+	}
+	if !reflect.DeepEqual(sink.counts, expected) {
+		t.Errorf("Expected %v, got %v", expected, sink.counts)
+	}
+}
+
+func TestMetrics_NoModification(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	sink := &fakeMetricsSink{counts: map[string]int{}}
+	handler := newHandler(t, cfg, nil)
+	handler.SetMetricsSink(sink)
+
+	serve(handler, http.MethodGet, "http://localhost?b=1")
+
+	if len(sink.counts) != 0 {
+		t.Errorf("Expected no counts, got %v", sink.counts)
+	}
+}
+
+type fakeRuleMetricsSink struct {
+	fakeMetricsSink
+}
+
+func (f *fakeRuleMetricsSink) IncRule(ruleName, modificationType, paramName string) {
+	f.counts[ruleName+":"+modificationType+":"+paramName]++
+}
+
+func TestMetrics_RuleNames(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "delete", ParamNameRegex: "^utm_", Name: "strip-tracking"},
+		{Type: "modify", ParamName: "a", NewValue: "c"},
+	}
+	sink := &fakeRuleMetricsSink{fakeMetricsSink{counts: map[string]int{}}}
+	handler := newHandler(t, cfg, nil)
+	handler.SetMetricsSink(sink)
+
+	serve(handler, http.MethodGet, "http://localhost?a=b&utm_source=x")
+
+	expected := map[string]int{
+		"strip-tracking:delete:utm_source": 1,
+		"rules[1]:modify:a":                1,
+	}
+	if !reflect.DeepEqual(sink.counts, expected) {
+		t.Errorf("Expected %v, got %v", expected, sink.counts)
+	}
+}
+
+type fakeRuleOutcomeSink struct {
+	fakeMetricsSink
+}
+
+func (f *fakeRuleOutcomeSink) IncMatched(ruleName string) {
+	f.counts["matched:"+ruleName]++
+}
+
+func (f *fakeRuleOutcomeSink) IncApplied(ruleName string) {
+	f.counts["applied:"+ruleName]++
+}
+
+func (f *fakeRuleOutcomeSink) IncSkipped(ruleName string) {
+	f.counts["skipped:"+ruleName]++
+}
+
+func TestMetrics_RuleOutcomes(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "delete", ParamName: "token", ParamValueRegex: "^secret$", Name: "strip-secret"},
+		{Type: "delete", ParamName: "debug", PathRegex: "^/api/", Name: "api-only"},
+	}
+	sink := &fakeRuleOutcomeSink{fakeMetricsSink{counts: map[string]int{}}}
+	handler := newHandler(t, cfg, nil)
+	handler.SetMetricsSink(sink)
+
+	for _, target := range []string{
+		"http://localhost/api/items?token=secret&debug=1",
+		"http://localhost/api/items?token=other",
+		"http://localhost/web?token=secret&debug=1",
+	} {
+		serve(handler, http.MethodGet, target)
+	}
+
+	expected := map[string]int{
+		"delete:token":         2,
+		"delete:debug":         1,
+		"matched:strip-secret": 3,
+		"applied:strip-secret": 2,
+		"skipped:strip-secret": 1,
+		"matched:api-only":     2,
+		"applied:api-only":     1,
+		"skipped:api-only":     1,
+	}
+	if !reflect.DeepEqual(sink.counts, expected) {
+		t.Errorf("Expected %v, got %v", expected, sink.counts)
+	}
+}
@@ -0,0 +1,61 @@
+package traefik_plugin_parameters
+
+import (
+	"sort"
+	"strings"
+)
+
+// nameCase selects the case all query param names are converted to after the rules were applied
+type nameCase string
+
+const (
+	lowerNameCase nameCase = "lower"
+	upperNameCase nameCase = "upper"
+)
+
+func (c nameCase) isValid() bool {
+	switch c {
+	case lowerNameCase, upperNameCase, "":
+		return true
+	}
+
+	return false
+}
+
+func (c nameCase) convert(name string) string {
+	if c == upperNameCase {
+		return strings.ToUpper(name)
+	}
+	return strings.ToLower(name)
+}
+
+// convertNameCase converts the names of the given params to nameCase. Params whose names fold to the same name
+// are merged by concatenating their values in the order of their sorted original names.
+// Renamed params are added to replaced, so they keep their position with preserveOrder.
+// It reports whether any name was changed.
+func (q *QueryModification) convertNameCase(params map[string][]string, replaced map[string]string) bool {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if q.config.NameCase.convert(key) != key {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return false
+	}
+
+	// map iteration order is random, sorting keeps the order of merged values reproducible
+	sort.Strings(keys)
+	for _, key := range keys {
+		converted := q.config.NameCase.convert(key)
+		if _, ok := params[converted]; ok {
+			q.logger.Debugf("msg=\"merging params whose names differ in case\" param=%q into=%q", key, converted)
+		}
+		params[converted] = append(params[converted], params[key]...)
+		delete(params, key)
+		if replaced != nil {
+			replaced[key] = converted
+		}
+	}
+	return true
+}
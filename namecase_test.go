@@ -0,0 +1,72 @@
+package traefik_plugin_parameters_test
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestNameCase_Lower(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.NameCase = "lower"
+
+	assertQueryModification(t, cfg, "Page=2&SORT=asc&id=1", "id=1&page=2&sort=asc")
+}
+
+func TestNameCase_Upper(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.NameCase = "upper"
+
+	assertQueryModification(t, cfg, "Page=2&id=1", "ID=1&PAGE=2")
+}
+
+func TestNameCase_CollisionConcatenatesValues(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.NameCase = "lower"
+
+	// the values of the already lowercase name come first, followed by the others sorted by their original name
+	assertQueryModification(t, cfg, "Tag=b&tag=a&TAG=c&x=1", "tag=a&tag=c&tag=b&x=1")
+}
+
+func TestNameCase_AfterRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "Debug"
+	cfg.NameCase = "lower"
+
+	// the rules match the original names
+	assertQueryModification(t, cfg, "Debug=1&debug=2&A=1", "a=1&debug=2")
+}
+
+func TestNameCase_PreserveOrder(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.NameCase = "lower"
+	cfg.PreserveOrder = true
+
+	assertRawQueryModification(t, cfg, "z=1&Page=2&a=3", "z=1&page=2&a=3")
+}
+
+func TestNameCase_Apply(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.NameCase = "lower"
+	handler := newHandler(t, cfg, nil)
+
+	result := handler.Apply(url.Values{"Foo": {"1"}, "foo": {"2"}})
+	expected := url.Values{"foo": {"2", "1"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestNameCase_Invalid(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.NameCase = "title"
+
+	err := traefik_plugin_parameters.ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "invalid nameCase") {
+		t.Errorf("expected an error about nameCase, got %v", err)
+	}
+}
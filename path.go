@@ -0,0 +1,40 @@
+package traefik_plugin_parameters
+
+import (
+	"net/url"
+	"strings"
+)
+
+// rewritePath renders PathTemplate with the first targeted value of the params affected by this rule,
+// replacing $1 by the escaped value and ${path} by the given current escaped path.
+// It reports false if no value is targeted or the value could escape the path segment it is inserted into.
+func (r *rule) rewritePath(path string, qry url.Values, state *requestState) (string, bool) {
+	for _, key := range determineAffectedParams(qry, r, state) {
+		for _, value := range qry[key] {
+			if !r.matchesValue(key, value, state) {
+				continue
+			}
+
+			if strings.Contains(value, "/") || value == "." || value == ".." {
+				r.logger.Warnf("msg=\"value cannot be used in path, leaving path unchanged\" param=%q value=%q", key, value)
+				return "", false
+			}
+			return strings.NewReplacer("$1", url.PathEscape(value), "${path}", path).Replace(r.config.PathTemplate), true
+		}
+	}
+	return "", false
+}
+
+// setEscapedPath sets the path of the given URL to the given escaped path.
+// RawPath is only kept if the path cannot be restored from the unescaped one, like url.Parse does.
+func setEscapedPath(u *url.URL, escapedPath string) error {
+	path, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		return err
+	}
+	u.Path, u.RawPath = path, escapedPath
+	if (&url.URL{Path: path}).EscapedPath() == escapedPath {
+		u.RawPath = ""
+	}
+	return nil
+}
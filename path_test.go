@@ -0,0 +1,111 @@
+package traefik_plugin_parameters_test
+
+import (
+	"net/url"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestPathTemplate_MovesParamIntoPath(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "version"
+	cfg.PathTemplate = "/v$1${path}"
+
+	assertPathRewrite(t, cfg, "/items", "version=2&a=1", "/v2/items", "a=1", "/v2/items?a=1")
+}
+
+func TestPathTemplate_KeepsQueryWithModify(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "version"
+	cfg.NewValue = "$1"
+	cfg.PathTemplate = "/v$1${path}"
+
+	assertPathRewrite(t, cfg, "/items", "version=2", "/v2/items", "version=2", "/v2/items?version=2")
+}
+
+func TestPathTemplate_MissingParam(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "version"
+	cfg.PathTemplate = "/v$1${path}"
+
+	assertPathRewrite(t, cfg, "/items", "a=1", "/items", "a=1", "/items?a=1")
+}
+
+func TestPathTemplate_OnlyFirstValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "version"
+	cfg.PathTemplate = "/v$1${path}"
+
+	assertPathRewrite(t, cfg, "/items", "version=2&version=3", "/v2/items", "", "/v2/items")
+}
+
+func TestPathTemplate_RejectsSlashInValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "version"
+	cfg.PathTemplate = "/v$1${path}"
+
+	assertPathRewrite(t, cfg, "/items", "version=..%2Fadmin", "/items", "", "/items")
+}
+
+func TestPathTemplate_KeepsEscapedPath(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "version"
+	cfg.PathTemplate = "/v$1${path}"
+
+	assertPathRewrite(t, cfg, "/a%2Fb/c", "version=2", "/v2/a%2Fb/c", "", "/v2/a%2Fb/c")
+}
+
+func TestPathTemplate_EscapesValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "version"
+	cfg.PathTemplate = "/v$1${path}"
+
+	assertPathRewrite(t, cfg, "/items", "version=2%3F%25+b", "/v2%3F%25%20b/items", "", "/v2%3F%25%20b/items")
+}
+
+func TestPathTemplate_ErrorWithoutLeadingSlash(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "version"
+	cfg.PathTemplate = "v$1"
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+// assertPathRewrite serves a request with the given escaped path and query and compares the escaped path afterwards
+func assertPathRewrite(t *testing.T, cfg *traefik_plugin_parameters.Config, path, query, expectedPath, expectedQuery, expectedRequestURI string) {
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	parsed, err := url.Parse(path)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req.URL.Path, req.URL.RawPath = parsed.Path, parsed.RawPath
+	req.URL.RawQuery = query
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.EscapedPath() != expectedPath {
+		t.Errorf("Expected path %s, got %s", expectedPath, req.URL.EscapedPath())
+	}
+	if req.URL.RawQuery != expectedQuery {
+		t.Errorf("Expected query %s, got %s", expectedQuery, req.URL.RawQuery)
+	}
+	if req.RequestURI != expectedRequestURI {
+		t.Errorf("Expected request URI %s, got %s", expectedRequestURI, req.RequestURI)
+	}
+}
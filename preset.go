@@ -0,0 +1,64 @@
+package traefik_plugin_parameters
+
+import (
+	"errors"
+	"strings"
+)
+
+// presetType selects a built-in list of params deleted by a rule
+type presetType string
+
+const trackingParamsPreset presetType = "tracking-params"
+
+// trackingParams are the marketing and analytics params deleted by the tracking-params preset, in glob syntax
+var trackingParams = []string{
+	"utm_*", "fbclid", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "msclkid", "yclid", "twclid", "ttclid",
+	"li_fat_id", "igshid", "mc_cid", "mc_eid", "_ga", "_gl", "_hsenc", "_hsmi", "__hssc", "__hstc", "__hsfp",
+	"hsCtaTracking", "mkt_tok", "oly_anon_id", "oly_enc_id", "rb_clickid", "s_cid", "vero_conv", "vero_id",
+	"wickedid", "_openstat", "ref_src",
+}
+
+func (p presetType) isValid() bool {
+	switch p {
+	case trackingParamsPreset, "":
+		return true
+	}
+
+	return false
+}
+
+// params returns the globs of the params deleted by the preset
+func (p presetType) params() []string {
+	if p == trackingParamsPreset {
+		return trackingParams
+	}
+	return nil
+}
+
+// resolvePreset validates the preset of the given configuration and returns a copy deleting the params of the preset
+// and extraParams by a paramNameRegex. The type defaults to delete.
+func resolvePreset(config *RuleConfig) (*RuleConfig, error) {
+	if !config.Preset.isValid() {
+		return nil, errors.New("invalid preset, expected tracking-params")
+	}
+	if config.Preset == "" {
+		return nil, errors.New("extraParams can only be used together with preset")
+	}
+	if config.Type != "" && config.Type != deleteType {
+		return nil, errors.New("preset can only be used with type delete")
+	}
+	if containsNonEmpty(config.ParamName, config.ParamNameRegex, config.ParamNameGlob, config.ParamNamePrefix, config.ParamNameSuffix) {
+		return nil, errors.New("preset cannot be used together with name matchers")
+	}
+
+	globs := append(append([]string(nil), config.Preset.params()...), config.ExtraParams...)
+	alternatives := make([]string, 0, len(globs))
+	for _, glob := range globs {
+		alternatives = append(alternatives, globToRegex(glob))
+	}
+
+	resolved := *config
+	resolved.Type = deleteType
+	resolved.ParamNameRegex = strings.Join(alternatives, "|")
+	return &resolved, nil
+}
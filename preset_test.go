@@ -0,0 +1,78 @@
+package traefik_plugin_parameters_test
+
+import (
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestPreset_TrackingParams(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Preset = "tracking-params"
+	previous := "utm_source=news&utm_medium=mail&fbclid=abc&gclid=def&mc_eid=1&id=42&q=shoes&utm=keep"
+	expected := "id=42&q=shoes&utm=keep"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreset_ExtraParams(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Preset = "tracking-params"
+	cfg.ExtraParams = []string{"ref", "campaign_*"}
+	previous := "ref=home&campaign_id=7&utm_campaign=x&page=2"
+	expected := "page=2"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreset_ExplicitDeleteType(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "delete", Preset: "tracking-params"},
+		{Type: "add", ParamName: "clean", NewValue: "1"},
+	}
+
+	assertQueryModification(t, cfg, "gclid=1&a=1", "a=1&clean=1")
+}
+
+func TestPreset_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    traefik_plugin_parameters.RuleConfig
+		errMsg string
+	}{
+		{
+			name:   "unknown preset",
+			cfg:    traefik_plugin_parameters.RuleConfig{Preset: "ads"},
+			errMsg: "invalid preset, expected tracking-params",
+		},
+		{
+			name:   "extra params without preset",
+			cfg:    traefik_plugin_parameters.RuleConfig{Type: "delete", ExtraParams: []string{"ref"}},
+			errMsg: "extraParams can only be used together with preset",
+		},
+		{
+			name:   "other type",
+			cfg:    traefik_plugin_parameters.RuleConfig{Type: "modify", Preset: "tracking-params", NewValue: "x"},
+			errMsg: "preset can only be used with type delete",
+		},
+		{
+			name:   "name matcher",
+			cfg:    traefik_plugin_parameters.RuleConfig{Preset: "tracking-params", ParamNamePrefix: "ref"},
+			errMsg: "preset cannot be used together with name matchers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = tt.cfg
+
+			err := traefik_plugin_parameters.ValidateConfig(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
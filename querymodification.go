@@ -2,32 +2,74 @@ package traefik_plugin_parameters
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"math/rand"
 	"net/http"
-	"regexp"
+	"net/url"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-type modificationType string
-
-const (
-	addType        modificationType = "add"
-	modifyType     modificationType = "modify"
-	deleteType     modificationType = "delete"
-	addReplaceType modificationType = "add-or-replace"
-)
-
-// Config is the configuration for this plugin
+// Config is the configuration for this plugin.
+// The embedded RuleConfig describes a single rule, further rules can be given in Rules.
 type Config struct {
-	Type            modificationType `json:"type"`
-	ParamName       string           `json:"paramName"`
-	ParamNameRegex  string           `json:"paramNameRegex"`
-	ParamValueRegex string           `json:"paramValueRegex"`
-	NewValue        string           `json:"newValue"`
-	NewValueRegex   string           `json:"newValueRegex"`
+	// squash lets Traefik decode the fields of the single rule at the top level, like encoding/json does
+	RuleConfig         `mapstructure:",squash"`
+	Rules              []RuleConfig  `json:"rules"`
+	DryRun             bool          `json:"dryRun"`
+	PreserveOrder      bool          `json:"preserveOrder"`
+	PreserveEncoding   bool          `json:"preserveEncoding"`
+	MaxParams          int           `json:"maxParams"`
+	LogLevel           logLevel      `json:"logLevel"`
+	MaxRegexLength     int           `json:"maxRegexLength"`
+	DebugHeaderName    string        `json:"debugHeaderName"`
+	Dedupe             bool          `json:"dedupe"`
+	DedupeParams       []string      `json:"dedupeParams"`
+	VerifySignature    bool          `json:"verifySignature"`
+	SignatureParam     string        `json:"signatureParam"`
+	SignatureSecret    string        `json:"signatureSecret"`
+	SignedParams       []string      `json:"signedParams"`
+	AuditLog           bool          `json:"auditLog"`
+	StrictMatchers     bool          `json:"strictMatchers"`
+	RulesFile          string        `json:"rulesFile"`
+	SemicolonSeparator bool          `json:"semicolonSeparator"`
+	MaxBodyBytes       int64         `json:"maxBodyBytes"`
+	RejectLargeBody    bool          `json:"rejectLargeBody"`
+	SamplePercent      int           `json:"samplePercent"`
+	RequireParam       string        `json:"requireParam"`
+	MissingParamStatus int           `json:"missingParamStatus"`
+	OriginalQueryParam string        `json:"originalQueryParam"`
+	CollapseRepeated   bool          `json:"collapseRepeated"`
+	CollapseParams     []string      `json:"collapseParams"`
+	JoinSeparator      string        `json:"joinSeparator"`
+	SpaceEncoding      spaceEncoding `json:"spaceEncoding"`
+	RewriteRedirects   bool          `json:"rewriteRedirects"`
+	RedirectRules      []RuleConfig  `json:"redirectRules"`
+	RetryOnStatus      []int         `json:"retryOnStatus"`
+	RetryRules         []RuleConfig  `json:"retryRules"`
+	NameCase           nameCase      `json:"nameCase"`
+	LiteralChars       string        `json:"literalChars"`
+	RejectMalformed    bool          `json:"rejectMalformed"`
+	RejectExcessParams bool          `json:"rejectExcessParams"`
+	SortValues         bool          `json:"sortValues"`
+	SortParams         []string      `json:"sortParams"`
 }
 
+// errMalformedQuery is returned for queries which cannot be parsed strictly if rejectMalformed is set
+var errMalformedQuery = errors.New("query is malformed")
+
+// errTooManyParams is returned for queries with more than maxParams params if rejectExcessParams is set
+var errTooManyParams = errors.New("query exceeds maxParams")
+
+// defaultMaxRegexLength is the maximum length of regexes if maxRegexLength is not set
+const defaultMaxRegexLength = 1024
+
 // CreateConfig creates a new configuration for this plugin
 func CreateConfig() *Config {
 	return &Config{}
@@ -35,151 +77,687 @@ func CreateConfig() *Config {
 
 // QueryModification represents the basic properties of this plugin
 type QueryModification struct {
-	next                    http.Handler
-	name                    string
-	config                  *Config
-	paramNameRegexCompiled  *regexp.Regexp
-	paramValueRegexCompiled *regexp.Regexp
+	// counter backs .Counter in newValueTemplate, it is accessed atomically and kept first for 64-bit alignment
+	counter uint64
+	next    http.Handler
+	name    string
+	config  *Config
+	rules   []*rule
+	metrics MetricsSink
+	hook    ModificationHook
+	logger  *logger
+	// redirectRules are applied to the query of redirect locations, they are nil without rewriteRedirects
+	redirectRules []*rule
+	// retryRules are applied to the query of retried requests, they are nil without retryOnStatus
+	retryRules []*rule
+	// literalChars restores the characters of literalChars in encoded queries, it is nil without literalChars
+	literalChars *strings.Replacer
+	// encode encodes the modified query, it is only replaced in tests
+	encode func(rawQuery string, qry url.Values, replaced map[string]string) string
+	// random draws the samples for samplePercent, it is guarded by randomMu as rand.Rand is not safe for concurrent use
+	random   *rand.Rand
+	randomMu sync.Mutex
 }
 
 // New creates a new instance of this plugin
 func New(ctx context.Context, next http.Handler, config *Config, name string) (http.Handler, error) {
-	if !config.Type.isValid() {
-		return nil, errors.New("invalid modification type, expected add / modify / delete")
+	config, err := cloneConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := newLogger(config.LogLevel, name)
+	rules, err := compileRules(config, logger)
+	if err != nil {
+		return nil, err
+	}
+	redirectRules, err := compileRedirectRules(config, rules, logger)
+	if err != nil {
+		return nil, err
+	}
+	retryRules, err := compileRetryRules(config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &QueryModification{
+		next:          next,
+		name:          name,
+		config:        config,
+		rules:         rules,
+		redirectRules: redirectRules,
+		retryRules:    retryRules,
+		metrics:       noopMetricsSink{},
+		logger:        logger,
+		random:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if config.LiteralChars != "" {
+		q.literalChars = literalCharsReplacer(config.LiteralChars)
+	}
+	q.encode = q.encodeQuery
+	return q, nil
+}
+
+// cloneConfig returns a deep copy of the given configuration, so the handler only reads its own copy
+// while serving requests and changes of the caller to the given configuration cannot race with them
+func cloneConfig(config *Config) (*Config, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not copy configuration: %w", err)
+	}
+	var clone Config
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("could not copy configuration: %w", err)
+	}
+	return &clone, nil
+}
+
+// ValidateConfig runs all checks of New on the given configuration without creating a handler,
+// e.g. to validate configurations before deploying them. Warnings about discouraged configurations are not logged.
+func ValidateConfig(config *Config) error {
+	logger := newLogger(noneLogLevel, "")
+	rules, err := compileRules(config, logger)
+	if err != nil {
+		return err
+	}
+	if _, err := compileRedirectRules(config, rules, logger); err != nil {
+		return err
+	}
+	_, err = compileRetryRules(config, logger)
+	return err
+}
+
+// compileRules validates the given configuration and creates its rules in the order they are applied.
+func compileRules(config *Config, logger *logger) ([]*rule, error) {
+	if !config.LogLevel.isValid() {
+		return nil, errors.New("invalid log level, expected none / warn / debug")
+	}
+
+	if config.VerifySignature && config.SignatureSecret == "" {
+		return nil, errors.New("signatureSecret must be set for verifySignature")
+	}
+
+	if config.RejectExcessParams && config.MaxParams <= 0 {
+		return nil, errors.New("maxParams must be set for rejectExcessParams")
+	}
+
+	if config.RejectLargeBody && config.MaxBodyBytes <= 0 {
+		return nil, errors.New("maxBodyBytes must be set for rejectLargeBody")
+	}
+
+	if config.MissingParamStatus != 0 {
+		if config.RequireParam == "" {
+			return nil, errors.New("missingParamStatus can only be used together with requireParam")
+		}
+		if config.MissingParamStatus < 400 || config.MissingParamStatus > 599 {
+			return nil, errors.New("missingParamStatus must be a client or server error status between 400 and 599")
+		}
+	}
+
+	if !config.CollapseRepeated && (len(config.CollapseParams) > 0 || config.JoinSeparator != "") {
+		return nil, errors.New("collapseParams and joinSeparator can only be used together with collapseRepeated")
 	}
 
-	if config.ParamNameRegex == "" && config.ParamName == "" && config.ParamValueRegex == "" {
-		return nil, errors.New("either paramNameRegex or paramName or paramValueRegex must be set")
+	if !config.Dedupe && len(config.DedupeParams) > 0 {
+		return nil, errors.New("dedupeParams can only be used together with dedupe")
 	}
 
-	if config.ParamNameRegex != "" && containsNonEmpty(config.ParamName, config.ParamValueRegex) ||
-		config.ParamName != "" && containsNonEmpty(config.ParamNameRegex, config.ParamValueRegex) ||
-		config.ParamValueRegex != "" && containsNonEmpty(config.ParamName, config.ParamNameRegex) {
-		log.Println("[Plugin Query Modification] It is discouraged to use multiple param matchers at once. Please proceed with caution")
+	if !config.SortValues && len(config.SortParams) > 0 {
+		return nil, errors.New("sortParams can only be used together with sortValues")
 	}
 
-	if config.NewValueRegex != "" && config.ParamValueRegex == "" {
-		return nil, errors.New("newValueRegex can only be used together with paramValueRegex")
+	if !config.SpaceEncoding.isValid() {
+		return nil, errors.New("invalid spaceEncoding, expected plus / percent")
 	}
 
-	var paramNameRegexCompiled *regexp.Regexp = nil
-	if config.ParamNameRegex != "" {
+	if !validLiteralChars(config.LiteralChars) {
+		return nil, fmt.Errorf("literalChars must only contain printable ASCII characters other than %q", reservedQueryChars)
+	}
+
+	if !config.NameCase.isValid() {
+		return nil, errors.New("invalid nameCase, expected lower / upper")
+	}
+
+	if config.SamplePercent < 0 || config.SamplePercent > 100 {
+		return nil, errors.New("samplePercent must be between 0 and 100")
+	}
+
+	maxRegexLength := config.maxRegexLength()
+
+	var fileRules []RuleConfig
+	if config.RulesFile != "" {
 		var err error
-		paramNameRegexCompiled, err = regexp.Compile(config.ParamNameRegex)
+		fileRules, err = readRulesFile(config.RulesFile)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	var paramValueRegexCompiled *regexp.Regexp = nil
-	if config.ParamValueRegex != "" {
-		var err error
-		paramValueRegexCompiled, err = regexp.Compile(config.ParamValueRegex)
+	var rules []*rule
+
+	// the top level rule is kept for backwards compatibility, it is optional if the plugin is only used
+	// to check signatures or required params, to keep the original query, to collapse repeated params,
+	// to convert the case of names, to dedupe or sort values or to rewrite redirects or retries with separate rules
+	rulesOptional := config.VerifySignature || config.RequireParam != "" || config.OriginalQueryParam != "" || config.CollapseRepeated ||
+		config.NameCase != "" || config.Dedupe || config.SortValues || len(config.RedirectRules) > 0 || len(config.RetryRules) > 0
+	if len(config.Rules) == 0 && len(fileRules) == 0 && !rulesOptional || config.RuleConfig.isSet() {
+		rs, err := newRules(&config.RuleConfig, logger, maxRegexLength, config.StrictMatchers)
 		if err != nil {
 			return nil, err
 		}
+		rules = append(rules, nameRules(rs, config.RuleConfig.Name, "rule", logger)...)
+	}
+
+	for i := range config.Rules {
+		rs, err := newRules(&config.Rules[i], logger, maxRegexLength, config.StrictMatchers)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		rules = append(rules, nameRules(rs, config.Rules[i].Name, fmt.Sprintf("rules[%d]", i), logger)...)
+	}
+
+	// the rules of the file are applied after the inline rules
+	for i := range fileRules {
+		rs, err := newRules(&fileRules[i], logger, maxRegexLength, config.StrictMatchers)
+		if err != nil {
+			return nil, fmt.Errorf("rulesFile[%d]: %w", i, err)
+		}
+		rules = append(rules, nameRules(rs, fileRules[i].Name, fmt.Sprintf("rulesFile[%d]", i), logger)...)
+	}
+	return rules, nil
+}
+
+// maxRegexLength returns the maximum length of regexes, which is maxRegexLength or defaultMaxRegexLength if not set
+func (c *Config) maxRegexLength() int {
+	if c.MaxRegexLength <= 0 {
+		return defaultMaxRegexLength
 	}
+	return c.MaxRegexLength
+}
 
-	return &QueryModification{
-		next:                    next,
-		name:                    name,
-		config:                  config,
-		paramNameRegexCompiled:  paramNameRegexCompiled,
-		paramValueRegexCompiled: paramValueRegexCompiled,
-	}, nil
+// nameRules sets the given name on the given rules, or the given default name based on their position if it is empty.
+// The name is added to the log messages of the rules, to their audit log entries and to their metrics.
+func nameRules(rules []*rule, name, defaultName string, logger *logger) []*rule {
+	if name == "" {
+		name = defaultName
+	}
+	for _, r := range rules {
+		r.name = name
+		r.logger = logger.forRule(name)
+	}
+	return rules
 }
 
 func (q *QueryModification) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	if req.Method == "GET" || req.Method == "" {
-		qry := req.URL.Query()
-		switch q.config.Type {
-		case addType:
-			qry.Add(q.config.ParamName, q.config.NewValue)
-		case deleteType:
-			paramsToDelete := determineAffectedParams(req, q)
-			for _, paramToDelete := range paramsToDelete {
-				qry.Del(paramToDelete)
+	if q.config.VerifySignature && !q.checkSignature(req) {
+		http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if q.config.RequireParam != "" && !q.checkRequiredParam(req) {
+		status := q.missingParamStatus()
+		http.Error(rw, http.StatusText(status), status)
+		return
+	}
+
+	// a cancelled request is forwarded untouched instead of spending time on the modifications,
+	// the signature is checked before so that cancelling a request cannot bypass it
+	if err := req.Context().Err(); err != nil {
+		q.logger.Debugf("msg=\"request context is done, forwarding the request unchanged\" error=%q", err)
+		q.next.ServeHTTP(rw, withQueries(req, req.URL.RawQuery))
+		return
+	}
+
+	if !q.sampled() {
+		q.next.ServeHTTP(rw, withQueries(req, req.URL.RawQuery))
+		return
+	}
+
+	originalQuery := req.URL.RawQuery
+	applied, err := q.modifyRequest(req, nil)
+	if errors.Is(err, errBodyTooLarge) {
+		http.Error(rw, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if errors.Is(err, errMalformedQuery) || errors.Is(err, errTooManyParams) {
+		http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	req = withQueries(req, originalQuery)
+	if q.config.DebugHeaderName != "" && len(applied) > 0 {
+		rw.Header().Set(q.config.DebugHeaderName, strings.Join(applied, ","))
+	}
+
+	if q.redirectRules != nil {
+		rw = &redirectWriter{ResponseWriter: rw, rewrite: func(location string) string { return q.rewriteLocation(req, location) }}
+	}
+
+	if q.retryRules != nil && req.Method == http.MethodGet && !isUpgrade(req) {
+		q.serveWithRetry(rw, req)
+		return
+	}
+
+	q.next.ServeHTTP(rw, req)
+}
+
+// sampled reports whether the current request is modified according to samplePercent, unset means all requests are.
+func (q *QueryModification) sampled() bool {
+	if q.config.SamplePercent == 0 || q.config.SamplePercent == 100 {
+		return true
+	}
+
+	q.randomMu.Lock()
+	defer q.randomMu.Unlock()
+	return q.random.Intn(100) < q.config.SamplePercent
+}
+
+// modifyRequest applies all rules in order to the given request.
+// The query is parsed once before the first rule and encoded once after the last rule.
+// Requests with a malformed query are left untouched, as are requests whose modified query would be malformed.
+// In dry run mode the modifications are only logged and the request is left untouched.
+// It returns the applied modifications in the form type=param,
+// or errBodyTooLarge if the form body exceeds maxBodyBytes and rejectLargeBody is set,
+// errMalformedQuery if the query cannot be parsed and rejectMalformed is set,
+// or errTooManyParams if the query exceeds maxParams and rejectExcessParams is set.
+// If explained is not nil, the modifications are appended to it instead, regardless of dry run mode,
+// and the request is left untouched. Neither metrics nor the audit log are written in this case.
+func (q *QueryModification) modifyRequest(req *http.Request, explained *[]auditModification) ([]string, error) {
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	// record tells whether the values before and after each rule are kept for the audit log or explained
+	record := q.config.AuditLog || explained != nil
+
+	// the headers are modified on a copy, which replaces the original ones once all modifications succeeded
+	header := req.Header.Clone()
+
+	// url.Query silently drops malformed params, the request is rather left untouched than altered unnoticed
+	rawQuery := q.splitQuery(req.URL.RawQuery)
+	qry, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		if q.config.RejectMalformed && explained == nil {
+			if !q.config.DryRun {
+				q.logger.Debugf("msg=\"could not parse query, rejecting the request\" error=%q", err)
+				return nil, errMalformedQuery
+			}
+			q.logger.Warnf("msg=\"dry run\" target=query error=%q result=%q", err, "rejected")
+			return nil, nil
+		}
+		q.logger.Warnf("msg=\"could not parse query, leaving the request unchanged\" error=%q", err)
+		return nil, nil
+	}
+
+	if q.config.MaxParams > 0 && len(qry) > q.config.MaxParams {
+		if q.config.RejectExcessParams && explained == nil {
+			if !q.config.DryRun {
+				q.logger.Debugf("msg=\"query exceeds maxParams, rejecting the request\" params=%d", len(qry))
+				return nil, errTooManyParams
+			}
+			q.logger.Warnf("msg=\"dry run\" target=maxParams params=%d result=%q", len(qry), "rejected")
+			return nil, nil
+		}
+		// too many params to handle, leave the request untouched
+		q.logger.Warnf("msg=\"query exceeds maxParams, leaving the request unchanged\" params=%d", len(qry))
+		return nil, nil
+	}
+
+	var form url.Values
+	var originalBody []byte
+	formParsed := false
+	var formErr error
+	parseForm := func() url.Values {
+		if !formParsed && isFormRequest(req) {
+			form, originalBody, formErr = readFormBody(req, q.config.MaxBodyBytes)
+			if formErr != nil {
+				q.logger.Warnf("msg=\"could not read form body, leaving it unchanged\" error=%q", formErr)
+			}
+		}
+		formParsed = true
+		return form
+	}
+
+	var doc map[string]interface{}
+	var originalJSON []byte
+	jsonParsed := false
+	var jsonErr error
+	parseJSON := func() map[string]interface{} {
+		if !jsonParsed && isJSONRequest(req) {
+			doc, originalJSON, jsonErr = readJSONBody(req, q.config.MaxBodyBytes)
+			if jsonErr != nil {
+				q.logger.Warnf("msg=\"could not read JSON body, leaving it unchanged\" error=%q", jsonErr)
+			}
+		}
+		jsonParsed = true
+		return doc
+	}
+
+	// replaced maps params replaced by add-or-replace to the new param taking their position
+	replaced := make(map[string]string)
+	counter := &q.counter
+	if explained != nil {
+		// explaining previews the value of the next request without drawing it
+		next := atomic.LoadUint64(&q.counter)
+		counter = &next
+	}
+	state := &requestState{req: req, header: header, counter: counter}
+	var applied []string
+	var audited []auditModification
+	// the path is modified in its escaped form, so escaped separators stay part of the segments
+	path := req.URL.EscapedPath()
+	queryModified, formModified, jsonModified, pathModified := false, false, false, false
+	for _, r := range q.rules {
+		if !r.appliesTo(req) || r.hasQueryCondition() && !r.queryConditionMet(qry) {
+			continue
+		}
+
+		if r.config.MatchRawName && state.rawNames == nil {
+			state.rawNames = rawNames(rawQuery)
+		}
+		if r.config.MatchRawValue && state.rawValues == nil {
+			state.rawValues = rawValues(rawQuery)
+		}
+		if r.config.hasEmptyValueMatcher() && state.emptyForms == nil {
+			state.emptyForms = rawEmptyForms(rawQuery)
+		}
+
+		var changed []string
+		// before and after hold the values of the modified params for the audit log
+		var before, after map[string][]string
+		switch {
+		case r.config.Target == headerTarget:
+			before, after = snapshotParams(record, header), header
+			changed = q.applyWithHook(req.Context(), header, func() []string { return r.modifyParams(header, state) })
+		case r.config.Target == formTarget:
+			if parseForm() == nil {
+				if q.config.RejectLargeBody && !q.config.DryRun && errors.Is(formErr, errBodyTooLarge) {
+					return nil, formErr
+				}
+				// no form body or the body could not be parsed
+				continue
+			}
+			before, after = snapshotParams(record, form), form
+			changed = q.applyWithHook(req.Context(), form, func() []string { return r.modifyParams(form, state) })
+			formModified = true
+		case r.config.Target == jsonTarget:
+			if parseJSON() == nil {
+				if q.config.RejectLargeBody && !q.config.DryRun && errors.Is(jsonErr, errBodyTooLarge) {
+					return nil, jsonErr
+				}
+				// no JSON object or the body could not be parsed
+				continue
+			}
+			changed, before, after = r.modifyJSON(doc, state, func(path string, oldValues, newValues []string) bool {
+				return q.hook == nil || q.hook.Allow(req.Context(), path, oldValues, newValues)
+			})
+			jsonModified = jsonModified || len(changed) > 0
+		case r.config.Target == matrixTarget:
+			base, matrix, order := splitMatrix(path)
+			before, after = snapshotParams(record, matrix), matrix
+			changed = q.applyWithHook(req.Context(), matrix, func() []string { return r.modifyParams(matrix, state) })
+			if len(changed) == 0 {
+				break
 			}
-		case addReplaceType:
-			paramsToDelete := determineAffectedParams(req, q)
-			for _, paramToDelete := range paramsToDelete {
-				qry.Del(paramToDelete)
+			newPath, err := joinMatrix(base, matrix, order)
+			if err != nil {
+				q.logger.Warnf("msg=\"could not encode matrix params, leaving the path unchanged\" error=%q", err)
+				changed = nil
+				break
 			}
-			qry.Add(q.config.ParamName, q.config.NewValue)
-		case modifyType:
-			paramsToModify := determineAffectedParams(req, q)
-			for _, paramToModify := range paramsToModify {
-				// use "old" query to prevent unwanted side effects
-				oldValues := req.URL.Query()[paramToModify]
-				var newValues []string
-				for _, oldValue := range oldValues {
-					var newValue string
-					if q.paramValueRegexCompiled == nil || q.paramValueRegexCompiled.MatchString(oldValue) {
-						if q.paramValueRegexCompiled != nil && q.config.NewValueRegex != "" {
-							// case 1: The regex for the query value matches and NewValueRegex is not empty
-							// then use these to determine the new value
-							newValue = q.paramValueRegexCompiled.ReplaceAllString(oldValue, q.config.NewValueRegex)
-						} else {
-							// case 2: There is no regex for the query value or it didn't match
-							// (because the query key is in here for some other reason (i.e. the key matches)
-							// then use the non-regex as replacement (maybe replace "$1" with the old value)
-							newValue = strings.ReplaceAll(q.config.NewValue, "$1", oldValue)
-						}
-					} else {
-						// case 3: There is a value regex which didn't match
-						// we do nothing then
-						newValue = oldValue
-					}
-					newValues = append(newValues, newValue)
+			path = newPath
+			pathModified = true
+		default:
+			if r.config.PathTemplate != "" {
+				// the path is rendered before the modification, which may remove the value
+				if newPath, ok := r.rewritePath(path, qry, state); ok {
+					q.logger.Debugf("msg=\"rewrote path\" before=%q after=%q", path, newPath)
+					path = newPath
+					pathModified = true
 				}
-				qry[paramToModify] = newValues
 			}
+			before, after = snapshotParams(record, qry), qry
+			_, existed := qry[r.paramKey()]
+			changed = q.applyQueryRule(req.Context(), r, qry, state)
+			if r.config.Type == addReplaceType && !existed && len(changed) == 2 {
+				// a single differently named param was replaced, the new param takes its position
+				replaced[changed[0]] = changed[1]
+			}
+			queryModified = true
+		}
+
+		if len(changed) > 0 && explained == nil {
+			r.logger.Debugf("msg=\"applied modification\" type=%s target=%s params=%q", r.config.Type, r.config.Target.orDefault(), changed)
+		}
+
+		if explained != nil {
+			*explained = append(*explained, auditModifications(r, changed, before, after)...)
+		} else if !q.config.DryRun {
+			q.countOutcome(r, changed)
+			for _, paramName := range changed {
+				q.incMetrics(r, paramName)
+				applied = append(applied, string(r.config.Type)+"="+paramName)
+			}
+			if q.config.AuditLog {
+				audited = append(audited, auditModifications(r, changed, before, after)...)
+			}
+		}
+	}
+
+	if q.normalizeQuery(qry, replaced) {
+		queryModified = true
+	}
+
+	if q.config.OriginalQueryParam != "" {
+		// set after all rules, so neither the rules nor a param sent by the client can alter the original query
+		qry.Set(q.config.OriginalQueryParam, req.URL.RawQuery)
+		queryModified = true
+	}
+
+	if !queryModified {
+		qry = nil
+	}
+	if !formModified {
+		form = nil
+	}
+
+	var jsonBody []byte
+	if jsonModified {
+		jsonBody, err = encodeJSON(doc)
+		if err != nil {
+			q.logger.Warnf("msg=\"could not encode JSON body, leaving it unchanged\" error=%q", err)
+			jsonBody = nil
+		}
+	}
 
+	if q.config.DryRun && explained == nil {
+		if pathModified {
+			q.logger.Warnf("msg=\"dry run\" target=path before=%q after=%q", req.URL.EscapedPath(), path)
 		}
+		if jsonBody != nil {
+			q.logger.Warnf("msg=\"dry run\" target=json before=%q after=%q", originalJSON, jsonBody)
+		}
+		q.logDryRun(req, qry, form, originalBody, header, replaced)
+		return nil, nil
+	}
+
+	modifiedQuery := req.URL.RawQuery
+	if qry != nil {
+		modifiedQuery = q.encode(rawQuery, qry, replaced)
+		// fail safe: a query which cannot be parsed again is never forwarded
+		if _, err := url.ParseQuery(modifiedQuery); err != nil {
+			q.logger.Warnf("msg=\"modified query is malformed, leaving the request unchanged\" query=%q error=%q", modifiedQuery, err)
+			if explained != nil {
+				*explained = nil
+			}
+			return nil, nil
+		}
+		modifiedQuery = q.joinQuery(modifiedQuery)
+	}
 
-		req.URL.RawQuery = qry.Encode()
+	if explained != nil {
+		return nil, nil
+	}
+
+	if len(audited) > 0 {
+		q.writeAuditLog(req.URL.Path, audited)
+	}
+
+	req.Header = header
+	if form != nil {
+		setBody(req, []byte(form.Encode()))
+	}
+	if jsonBody != nil {
+		setBody(req, jsonBody)
+	}
+	req.URL.RawQuery = modifiedQuery
+	if pathModified {
+		if err := setEscapedPath(req.URL, path); err != nil {
+			q.logger.Warnf("msg=\"modified path is malformed, leaving it unchanged\" path=%q error=%q", path, err)
+		}
+	}
+	if qry != nil || pathModified {
+		// the fragment is never part of the request URI, req.URL.Fragment itself is left untouched
 		req.RequestURI = req.URL.RequestURI()
+	}
+
+	return applied, nil
+}
 
-		q.next.ServeHTTP(rw, req)
+// dedupeValues removes duplicate values of each param listed in dedupeParams, or of all params if none are listed,
+// keeping the first occurrence. It reports whether any value was removed.
+func (q *QueryModification) dedupeValues(params map[string][]string) bool {
+	keys := q.config.DedupeParams
+	if len(keys) == 0 {
+		keys = make([]string, 0, len(params))
+		for key := range params {
+			keys = append(keys, key)
+		}
+	}
+
+	removed := false
+	for _, key := range keys {
+		values := params[key]
+		seen := make(map[string]bool, len(values))
+		deduped := make([]string, 0, len(values))
+		for _, value := range values {
+			if !seen[value] {
+				seen[value] = true
+				deduped = append(deduped, value)
+			}
+		}
+		if len(deduped) != len(values) {
+			params[key] = deduped
+			removed = true
+		}
 	}
+	return removed
 }
 
-func determineAffectedParams(req *http.Request, q *QueryModification) []string {
-	var result []string
-	for key, values := range req.URL.Query() {
-		if q.config.ParamName == key ||
-			(q.paramNameRegexCompiled != nil && q.paramNameRegexCompiled.MatchString(key)) ||
-			(q.paramValueRegexCompiled != nil && anyMatch(values, q.paramValueRegexCompiled)) {
-			result = append(result, key)
+// sortValues sorts the values of each param listed in sortParams, or of all params if none are listed, lexicographically.
+// It reports whether the order of any values was changed.
+func (q *QueryModification) sortValues(params map[string][]string) bool {
+	keys := q.config.SortParams
+	if len(keys) == 0 {
+		keys = make([]string, 0, len(params))
+		for key := range params {
+			keys = append(keys, key)
 		}
 	}
 
-	return result
+	sorted := false
+	for _, key := range keys {
+		if values := params[key]; !sort.StringsAreSorted(values) {
+			sort.Strings(values)
+			sorted = true
+		}
+	}
+	return sorted
 }
 
-func anyMatch(values []string, regex *regexp.Regexp) bool {
-	for _, value := range values {
-		if regex.MatchString(value) {
-			return true
+// defaultJoinSeparator separates the collapsed values if joinSeparator is not set
+const defaultJoinSeparator = ","
+
+// collapseValues joins the values of each param listed in collapseParams, or of all params if none are listed,
+// into a single value separated by joinSeparator. It reports whether any param was collapsed.
+func (q *QueryModification) collapseValues(params map[string][]string) bool {
+	separator := q.config.JoinSeparator
+	if separator == "" {
+		separator = defaultJoinSeparator
+	}
+
+	keys := q.config.CollapseParams
+	if len(keys) == 0 {
+		keys = make([]string, 0, len(params))
+		for key := range params {
+			keys = append(keys, key)
 		}
 	}
-	return false
+
+	collapsed := false
+	for _, key := range keys {
+		if values := params[key]; len(values) > 1 {
+			params[key] = []string{strings.Join(values, separator)}
+			collapsed = true
+		}
+	}
+	return collapsed
 }
 
-func (mt modificationType) isValid() bool {
-	switch mt {
-	case addType, modifyType, deleteType, addReplaceType, "":
-		return true
+// encodeQuery encodes the modified query, keeping the order or the encoding of the original raw query if configured.
+// When keeping the order, the params in replaced take the position of the original params they replaced.
+func (q *QueryModification) encodeQuery(rawQuery string, qry url.Values, replaced map[string]string) string {
+	// the space encoding only applies to re-encoded params, passed through params keep their raw form
+	escape := q.config.SpaceEncoding.escaper()
+	var encoded string
+	switch {
+	case q.config.PreserveEncoding:
+		original, _ := url.ParseQuery(rawQuery)
+		encoded = encodePreserving(rawQuery, original, qry, replaced, escape)
+	case q.config.PreserveOrder:
+		encoded = encodeOrdered(rawQuery, qry, replaced, escape)
+	default:
+		// without a raw query, all params are written sorted by name like url.Values.Encode does
+		encoded = encodeOrdered("", qry, nil, escape)
+	}
+
+	if q.literalChars != nil {
+		encoded = q.literalChars.Replace(encoded)
+	}
+	return encoded
+}
+
+// splitQuery returns the given raw query with semicolons replaced by ampersands if semicolonSeparator is set,
+// so it can be parsed strictly.
+func (q *QueryModification) splitQuery(rawQuery string) string {
+	if !q.config.SemicolonSeparator {
+		return rawQuery
 	}
+	return strings.ReplaceAll(rawQuery, ";", "&")
+}
 
-	return false
+// joinQuery reverts splitQuery for the given encoded query, joining all params with semicolons.
+// Ampersands and semicolons within names and values are escaped by the encoding, so only separators are replaced.
+func (q *QueryModification) joinQuery(rawQuery string) string {
+	if !q.config.SemicolonSeparator {
+		return rawQuery
+	}
+	return strings.ReplaceAll(rawQuery, "&", ";")
 }
 
-func containsNonEmpty(ss ...string) bool {
-	for _, s := range ss {
-		if s != "" {
-			return true
+// logDryRun logs the query, form body and headers the given request would have been modified to.
+func (q *QueryModification) logDryRun(req *http.Request, qry, form url.Values, originalBody []byte, header http.Header, replaced map[string]string) {
+	if qry != nil {
+		if modifiedQuery := q.joinQuery(q.encodeQuery(q.splitQuery(req.URL.RawQuery), qry, replaced)); modifiedQuery != req.URL.RawQuery {
+			q.logger.Warnf("msg=\"dry run\" target=query before=%q after=%q", req.URL.RawQuery, modifiedQuery)
 		}
 	}
-	return false
+
+	if form != nil {
+		if modifiedBody := form.Encode(); modifiedBody != string(originalBody) {
+			q.logger.Warnf("msg=\"dry run\" target=form before=%q after=%q", originalBody, modifiedBody)
+		}
+	}
+
+	if !reflect.DeepEqual(header, req.Header) {
+		q.logger.Warnf("msg=\"dry run\" target=header before=%q after=%q", req.Header, header)
+	}
 }
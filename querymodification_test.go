@@ -1,10 +1,17 @@
 package traefik_plugin_parameters_test
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -75,6 +82,163 @@ func TestAddQueryParam_Previous(t *testing.T) {
 	assertQueryModification(t, cfg, previous, expected)
 }
 
+func TestAddIfAbsentQueryParam_Absent(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-if-absent"
+	cfg.ParamName = "newparam"
+	cfg.NewValue = "newvalue"
+	expected := "a=b&newparam=newvalue"
+	previous := "a=b"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestAddIfAbsentQueryParam_Present(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-if-absent"
+	cfg.ParamName = "newparam"
+	cfg.NewValue = "newvalue"
+	expected := "a=b&newparam=clientvalue"
+	previous := "a=b&newparam=clientvalue"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestAddIfAbsentQueryParam_PresentEmpty(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-if-absent"
+	cfg.ParamName = "newparam"
+	cfg.NewValue = "newvalue"
+	expected := "newparam="
+	previous := "newparam="
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestAddQueryParam_ValueFromHeader(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "tenant"
+	cfg.ValueFromHeader = "X-Tenant-ID"
+	cfg.NewValue = "fallback"
+
+	assertValueFromHeader(t, cfg, "acme", "some=other", "some=other&tenant=acme")
+}
+
+func TestAddReplaceQueryParam_ValueFromHeader(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "tenant"
+	cfg.ValueFromHeader = "X-Tenant-ID"
+
+	assertValueFromHeader(t, cfg, "acme", "tenant=spoofed", "tenant=acme")
+}
+
+func TestAddQueryParam_ValueFromHeaderAbsentFallback(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "tenant"
+	cfg.ValueFromHeader = "X-Tenant-ID"
+	cfg.NewValue = "default"
+
+	assertValueFromHeader(t, cfg, "", "tenant=spoofed", "tenant=default")
+}
+
+func TestAddQueryParam_ValueFromHeaderAbsentSkipped(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "tenant"
+	cfg.ValueFromHeader = "X-Tenant-ID"
+
+	assertValueFromHeader(t, cfg, "", "tenant=spoofed", "tenant=spoofed")
+}
+
+func TestAddQueryParam_NewValues(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "tag"
+	cfg.NewValues = []string{"a", "b"}
+	previous := "tag=x"
+	expected := "tag=x&tag=a&tag=b"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestAddReplaceQueryParam_NewValuesTakePrecedence(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "tag"
+	cfg.NewValue = "ignored"
+	cfg.NewValues = []string{"a", "b"}
+	previous := "tag=x&other=1"
+	expected := "other=1&tag=a&tag=b"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestAddIfAbsentQueryParam_NewValues(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-if-absent"
+	cfg.ParamName = "tag"
+	cfg.NewValues = []string{"a", "b"}
+
+	assertQueryModification(t, cfg, "other=1", "other=1&tag=a&tag=b")
+	assertQueryModification(t, cfg, "tag=x", "tag=x")
+}
+
+func TestAddQueryParam_ValueFromEnv(t *testing.T) {
+	t.Setenv("QUERY_MODIFICATION_TEST_KEY", "secret")
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "key"
+	cfg.ValueFromEnv = "QUERY_MODIFICATION_TEST_KEY"
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	// the value is resolved when the middleware is created
+	if err := os.Unsetenv("QUERY_MODIFICATION_TEST_KEY"); err != nil {
+		t.Fatal(err)
+	}
+	req.URL.RawQuery = "key=spoofed"
+	handler.ServeHTTP(recorder, req)
+
+	if expected := "key=secret"; req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+}
+
+func TestAddQueryParam_ValueFromEnvUnset(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "key"
+	cfg.ValueFromEnv = "QUERY_MODIFICATION_TEST_UNSET"
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil || !strings.Contains(err.Error(), "QUERY_MODIFICATION_TEST_UNSET") {
+		t.Errorf("Expected error naming the unset variable, got %v", err)
+	}
+}
+
+func assertValueFromHeader(t *testing.T, cfg *traefik_plugin_parameters.Config, headerValue, previous, expected string) {
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	if headerValue != "" {
+		req.Header.Set("X-Tenant-ID", headerValue)
+	}
+	req.URL.RawQuery = previous
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+}
+
 // endregion
 
 //region Delete
@@ -128,6 +292,157 @@ func TestDeleteQueryParam_Readme(t *testing.T) {
 	assertQueryModification(t, cfg, previous, expected)
 }
 
+func TestDeleteQueryParam_OnlyMatchingValues(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamValueRegex = "^b$"
+	expected := "tag=a&tag=c"
+	previous := "tag=a&tag=b&tag=c"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestDeleteQueryParam_AllValuesMatching(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamValueRegex = "^[ab]$"
+	expected := "other=c"
+	previous := "tag=a&tag=b&other=c"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestDeleteQueryParam_ParamValueIn(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "env"
+	cfg.ParamValueIn = []string{"dev", "staging"}
+
+	assertQueryModification(t, cfg, "env=dev&a=1", "a=1")
+	assertQueryModification(t, cfg, "env=staging", "")
+	assertQueryModification(t, cfg, "env=prod&a=1", "a=1&env=prod")
+	assertQueryModification(t, cfg, "env=devel", "env=devel")
+}
+
+func TestDeleteQueryParam_ParamValueInCaseInsensitive(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamValueIn = []string{"dev", "sta.ging"}
+	cfg.CaseInsensitive = true
+
+	assertQueryModification(t, cfg, "env=DEV&a=1", "a=1")
+	assertQueryModification(t, cfg, "env=staxging", "env=staxging")
+}
+
+func TestDeleteQueryParam_MatchFirstOnly(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "tag"
+	cfg.MatchFirstOnly = true
+	expected := "tag=b&tag=c"
+	previous := "tag=a&tag=b&tag=c"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestClearQuery(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "clear"
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req.URL.Path = "/path"
+	req.URL.RawQuery = "a=1&b=2&b=3"
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.RawQuery != "" {
+		t.Errorf("Expected empty query, got %s", req.URL.RawQuery)
+	}
+	if req.RequestURI != "/path" {
+		t.Errorf("Expected request URI /path, got %s", req.RequestURI)
+	}
+}
+
+func TestClearQuery_Protected(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "clear"
+	cfg.ProtectedParams = []string{"keep"}
+	previous := "a=1&keep=2"
+	expected := "keep=2"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestDeleteQueryParam_NamePrefix(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNamePrefix = "utm_"
+	previous := "utm_source=news&UTM_MEDIUM=mail&id=1&x_utm_=2"
+	expected := "UTM_MEDIUM=mail&id=1&x_utm_=2"
+
+	assertQueryModification(t, cfg, previous, expected)
+
+	cfg.CaseInsensitive = true
+	assertQueryModification(t, cfg, previous, "id=1&x_utm_=2")
+}
+
+func TestDeleteQueryParam_NameSuffix(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameSuffix = "_token"
+	previous := "access_token=a&refresh_token=b&token=c&_token=d"
+	expected := "token=c"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestDeleteQueryParam_NamePrefixAndSuffix(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNamePrefix = "x_"
+	cfg.ParamNameSuffix = "_id"
+	previous := "x_user_id=1&x_user=2&user_id=3&x_id=4"
+	expected := "user_id=3&x_id=4&x_user=2"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestDeleteQueryParam_NegateNameMatch(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameRegex = "^(utm_|ref$)"
+	cfg.NegateNameMatch = true
+	previous := "utm_source=news&ref=home&session=42&id=1"
+	expected := "ref=home&utm_source=news"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestDeleteQueryParam_NegateNameMatchProtected(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "q"
+	cfg.NegateNameMatch = true
+	cfg.ProtectedParams = []string{"page"}
+	previous := "q=go&page=2&session=42"
+	expected := "page=2&q=go"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestDeleteQueryParam_NegateValueMatch(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamValueRegex = "^(en|de)$"
+	cfg.NegateValueMatch = true
+	previous := "lang=en&lang=xx&lang=de&region=de"
+	expected := "lang=en&lang=de&region=de"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
 //endregion
 
 // region Modify
@@ -230,57 +545,1986 @@ func TestModifyQueryParam_RegexValueMultiple(t *testing.T) {
 	assertQueryModification(t, cfg, previous, expected)
 }
 
-// endregion
-
-func TestErrorInvalidType(t *testing.T) {
+func TestModifyQueryParam_Protected(t *testing.T) {
 	cfg := traefik_plugin_parameters.CreateConfig()
-	cfg.Type = "bla"
-	cfg.ParamName = "blub"
-	ctx := context.Background()
-	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
-	_, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+	cfg.Type = "modify"
+	cfg.ParamNameRegex = ".*token$"
+	cfg.NewValue = "censored"
+	cfg.ProtectedParams = []string{"csrf_token"}
+	previous := "access_token=1&csrf_token=2"
+	expected := "access_token=censored&csrf_token=2"
 
-	if err == nil {
-		t.Error("expected error but err is nil")
-	}
+	assertQueryModification(t, cfg, previous, expected)
 }
 
-func TestErrorNoParam(t *testing.T) {
+func TestModifyQueryParam_ProtectedCaseInsensitive(t *testing.T) {
 	cfg := traefik_plugin_parameters.CreateConfig()
 	cfg.Type = "delete"
-	ctx := context.Background()
-	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
-	_, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+	cfg.ParamValueRegex = ".*"
+	cfg.CaseInsensitive = true
+	cfg.ProtectedParams = []string{"csrf_token"}
+	previous := "a=1&CSRF_Token=2"
+	expected := "CSRF_Token=2"
 
-	if err == nil {
-		t.Error("expected error but err is nil")
-	}
+	assertQueryModification(t, cfg, previous, expected)
 }
 
-func createReqAndRecorder(cfg *traefik_plugin_parameters.Config) (http.Handler, error, *httptest.ResponseRecorder, *http.Request) {
-	ctx := context.Background()
-	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
-	handler, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
-	if err != nil {
-		return nil, err, nil, nil
+func TestModifyQueryParam_MatchFirstOnly(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamValueRegex = "^[bc]$"
+	cfg.NewValue = "x"
+	cfg.MatchFirstOnly = true
+	previous := "tag=a&tag=b&tag=c"
+	expected := "tag=a&tag=x&tag=c"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_ValueIndex(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "tag"
+	cfg.NewValue = "x-$1"
+	index := 1
+	cfg.ValueIndex = &index
+	previous := "tag=a&tag=b&tag=c"
+	expected := "tag=a&tag=x-b&tag=c"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_ValueIndexAll(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "tag"
+	cfg.NewValue = "x"
+	index := -1
+	cfg.ValueIndex = &index
+	previous := "tag=a&tag=b"
+	expected := "tag=x&tag=x"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_ValueIndexNotMatching(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "tag"
+	cfg.ParamValueRegex = "^c$"
+	cfg.NewValue = "x"
+	index := 1
+	cfg.ValueIndex = &index
+	previous := "tag=a&tag=b&tag=c"
+	expected := "tag=a&tag=b&tag=c"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_ValueIndexOutOfRange(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "tag"
+	cfg.NewValue = "x"
+	index := 2
+	cfg.ValueIndex = &index
+
+	logged := serveAndCaptureLog(t, cfg, "tag=a&tag=b")
+
+	if !strings.Contains(logged, `msg="valueIndex is out of range, leaving the param unchanged" param="tag" valueIndex=2 values=2`) {
+		t.Errorf("Expected a warning about the index, got %s", logged)
 	}
+	assertQueryModification(t, cfg, "tag=a&tag=b", "tag=a&tag=b")
+}
 
-	recorder := httptest.NewRecorder()
+func TestModifyQueryParam_NameGroup(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamNameRegex = "^utm_(.*)$"
+	cfg.NewValue = "${name:1}-$1"
+	previous := "utm_source=google&utm_medium=cpc&other=1"
+	expected := "other=1&utm_medium=medium-cpc&utm_source=source-google"
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost", nil)
-	return handler, err, recorder, req
+	assertQueryModification(t, cfg, previous, expected)
 }
 
-func assertQueryModification(t *testing.T, cfg *traefik_plugin_parameters.Config, previous, expected string) {
-	handler, err, recorder, req := createReqAndRecorder(cfg)
-	if err != nil {
-		t.Fatal(err)
-		return
+func TestModifyQueryParam_NamedNameGroup(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamNameRegex = "^utm_(?P<kind>.*)$"
+	cfg.NewValue = "${name:kind}"
+	previous := "utm_source=google"
+	expected := "utm_source=source"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_NameGroupWithValueRegex(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "modify", ParamNameRegex: "^(id)_(.*)$", ParamValueRegex: "^(\\d+)$", NewValueRegex: "${name:2}:$1"},
 	}
-	req.URL.RawQuery = previous
-	handler.ServeHTTP(recorder, req)
+	previous := "id_user=42&id_group=abc"
+	expected := "id_group=abc&id_user=user%3A42"
 
-	if req.URL.Query().Encode() != expected {
-		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_CaseInsensitive(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "id"
+	cfg.CaseInsensitive = true
+	cfg.NewValue = "censored"
+	previous := "ID=1&Id=2&other=3"
+	expected := "ID=censored&Id=censored&other=3"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_CaseSensitiveByDefault(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "id"
+	cfg.NewValue = "censored"
+	previous := "ID=1&id=2"
+	expected := "ID=1&id=censored"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestAddReplaceQueryParam_CaseInsensitiveKeepsCasing(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "id"
+	cfg.CaseInsensitive = true
+	cfg.NewValue = "1"
+	previous := "ID=2"
+	expected := "id=1"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_ValueOnly(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ValueOnly = true
+	cfg.ParamValueRegex = `[\w.]+@[\w.]+`
+	cfg.NewValue = "redacted"
+	// the whole matching value is replaced, not only the matched part, the other value of a is left untouched
+	previous := "a=mail+john@example.com+now&a=plain&b=jane@example.com"
+	expected := "a=redacted&a=plain&b=redacted"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_ValueOnlyRegexReplace(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ValueOnly = true
+	cfg.ParamValueRegex = `([\w.]+)@[\w.]+`
+	cfg.NewValueRegex = "$1@redacted"
+	// the expanded newValueRegex becomes the whole new value
+	previous := "tag=keep&tag=mail+john@example.com"
+	expected := "tag=keep&tag=john%40redacted"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_ValueRegexReplaceWithoutValueOnly(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "tag"
+	cfg.ParamValueRegex = `([\w.]+)@[\w.]+`
+	cfg.NewValueRegex = "$1@redacted"
+	// without valueOnly, only the matched part of the value is replaced
+	previous := "tag=keep&tag=mail+john@example.com"
+	expected := "tag=keep&tag=mail+john%40redacted"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_ValueOnlyTransform(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ValueOnly = true
+	cfg.ParamValueRegex = "[a-z]+@"
+	cfg.Transform = "uppercase"
+	cfg.ValuePrefix = "<"
+	cfg.ValueSuffix = ">"
+	previous := "a=to+john@x&a=plain"
+	expected := "a=%3CTO+JOHN%40X%3E&a=plain"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_ValueMapMapped(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "c"
+	cfg.ValueMap = map[string]string{"ab": "autumn-blast", "sw": "spring-wave"}
+	previous := "c=ab&other=ab"
+	expected := "c=autumn-blast&other=ab"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyQueryParam_ValueMapUnmapped(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "c"
+	cfg.ValueMap = map[string]string{"ab": "autumn-blast"}
+	previous := "c=xy"
+	expected := "c=xy"
+
+	assertQueryModification(t, cfg, previous, expected)
+
+	cfg.DeleteUnmapped = true
+	assertQueryModification(t, cfg, "c=xy&other=1", "other=1")
+}
+
+func TestModifyQueryParam_ValueMapMultipleValues(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "c"
+	cfg.ValueMap = map[string]string{"ab": "autumn-blast", "sw": "spring-wave"}
+	cfg.DeleteUnmapped = true
+	previous := "c=sw&c=xy&c=ab"
+	expected := "c=spring-wave&c=autumn-blast"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+// endregion
+
+// region Rename
+func TestRenameQueryParam_Single(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "rename"
+	cfg.ParamName = "user_id"
+	cfg.NewName = "uid"
+	previous := "user_id=42&other=1"
+	expected := "other=1&uid=42"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestRenameQueryParam_Multiple(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "rename"
+	cfg.ParamName = "user_id"
+	cfg.NewName = "uid"
+	previous := "user_id=42&user_id=43"
+	expected := "uid=42&uid=43"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestRenameQueryParam_CollisionAppends(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "rename"
+	cfg.ParamName = "user_id"
+	cfg.NewName = "uid"
+	previous := "uid=1&user_id=42"
+	expected := "uid=1&uid=42"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestRenameQueryParam_CollisionReplaces(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "rename"
+	cfg.ParamName = "user_id"
+	cfg.NewName = "uid"
+	cfg.ReplaceExisting = true
+	previous := "uid=1&user_id=42"
+	expected := "uid=42"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestRenameQueryParam_DeterministicOrder(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "rename"
+	cfg.ParamNameRegex = "^id_"
+	cfg.NewName = "id"
+	cfg.PreserveOrder = true
+	previous := "id_c=3&id_a=1&id_d=4&id_b=2"
+	expected := "id=1&id=2&id=3&id=4"
+
+	for i := 0; i < 20; i++ {
+		assertRawQueryModification(t, cfg, previous, expected)
+	}
+}
+
+func TestRenameQueryParam_NotFound(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "rename"
+	cfg.ParamName = "user_id"
+	cfg.NewName = "uid"
+	cfg.ReplaceExisting = true
+	previous := "uid=1"
+	expected := "uid=1"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+// endregion
+
+// region Split
+func TestSplitQueryParam_Comma(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "split"
+	cfg.ParamName = "tags"
+	cfg.NewName = "tag"
+	previous := "tags=a,b,c&other=1"
+	expected := "other=1&tag=a&tag=b&tag=c"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestSplitQueryParam_CustomSeparator(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "split"
+	cfg.ParamName = "ids"
+	cfg.NewName = "id"
+	cfg.SplitSeparator = "|"
+	previous := "ids=1|2,3"
+	expected := "id=1&id=2%2C3"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestSplitQueryParam_SkipsEmptyParts(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "split"
+	cfg.ParamName = "tags"
+	cfg.NewName = "tag"
+	previous := "tags=a,,b,&tags=,"
+	expected := "tag=a&tag=b"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestSplitQueryParam_InPlace(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "split"
+	cfg.ParamName = "tags"
+	previous := "tags=a,b"
+	expected := "tags=a&tags=b"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestSplitQueryParam_AppendsToExisting(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "split"
+	cfg.ParamName = "tags"
+	cfg.NewName = "tag"
+	previous := "tag=x&tags=a,b"
+	expected := "tag=x&tag=a&tag=b"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestSplitQueryParam_SeparatorRequiresSplit(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "rename"
+	cfg.ParamName = "tags"
+	cfg.NewName = "tag"
+	cfg.SplitSeparator = "|"
+
+	_, err := traefik_plugin_parameters.New(context.Background(), nil, cfg, "query-modification-plugin")
+	if err == nil || !strings.Contains(err.Error(), "splitSeparator") {
+		t.Errorf("expected an error about splitSeparator, got %v", err)
+	}
+}
+
+// endregion
+
+// region Modify Or Add
+func TestModifyOrAddQueryParam_ModifiesMatchingValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify-or-add"
+	cfg.ParamName = "status"
+	cfg.ParamValueRegex = "^pending$"
+	cfg.NewValue = "queued"
+	previous := "status=done&status=pending&id=1"
+	expected := "id=1&status=done&status=queued"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyOrAddQueryParam_AddsWithoutMatchingValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify-or-add"
+	cfg.ParamName = "status"
+	cfg.ParamValueRegex = "^pending$"
+	cfg.NewValue = "queued"
+
+	assertQueryModification(t, cfg, "id=1", "id=1&status=queued")
+	assertQueryModification(t, cfg, "status=done", "status=done&status=queued")
+}
+
+func TestModifyOrAddQueryParam_OtherParamsUntouched(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify-or-add"
+	cfg.ParamName = "status"
+	cfg.ParamValueRegex = "^pending$"
+	cfg.NewValue = "queued"
+	cfg.StrictMatchers = true
+	previous := "previous=pending"
+	expected := "previous=pending&status=queued"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestModifyOrAddQueryParam_WithoutValueMatcher(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify-or-add"
+	cfg.ParamName = "page"
+	cfg.NewValue = "p$1"
+
+	assertQueryModification(t, cfg, "page=2", "page=p2")
+}
+
+func TestModifyOrAddQueryParam_ErrorNameMatcher(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify-or-add"
+	cfg.ParamName = "status"
+	cfg.ParamNamePrefix = "stat"
+	cfg.NewValue = "queued"
+
+	_, err := traefik_plugin_parameters.New(context.Background(), nil, cfg, "query-modification-plugin")
+	if err == nil || !strings.Contains(err.Error(), "modify-or-add") {
+		t.Errorf("expected an error about modify-or-add, got %v", err)
+	}
+}
+
+// endregion
+
+// region Methods
+func TestMethods_PostNotModifiedByDefault(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "newparam"
+	cfg.NewValue = "newvalue"
+	previous := "a=b"
+	expected := "a=b"
+
+	assertQueryModificationWithMethod(t, cfg, http.MethodPost, previous, expected)
+}
+
+func TestMethods_PostNotInList(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "newparam"
+	cfg.NewValue = "newvalue"
+	cfg.ApplyToMethods = []string{"GET", "PUT"}
+	previous := "a=b"
+	expected := "a=b"
+
+	assertQueryModificationWithMethod(t, cfg, http.MethodPost, previous, expected)
+}
+
+func TestMethods_PostInList(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "newparam"
+	cfg.NewValue = "newvalue"
+	cfg.ApplyToMethods = []string{"GET", "POST"}
+	previous := "a=b"
+	expected := "a=b&newparam=newvalue"
+
+	assertQueryModificationWithMethod(t, cfg, http.MethodPost, previous, expected)
+}
+
+func TestMethods_GetNotInList(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "newparam"
+	cfg.NewValue = "newvalue"
+	cfg.ApplyToMethods = []string{"post"}
+	previous := "a=b"
+	expected := "a=b"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestMethods_PostIsForwarded(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	ctx := context.Background()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte("downstream"))
+	})
+	handler := newHandler(t, cfg, next)
+
+	recorder := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost?a=b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.ServeHTTP(recorder, req)
+
+	if recorder.Body.String() != "downstream" {
+		t.Errorf("Expected body %s, got %s", "downstream", recorder.Body.String())
+	}
+	if req.URL.RawQuery != "a=b" {
+		t.Errorf("Expected %s, got %s", "a=b", req.URL.RawQuery)
+	}
+}
+
+// endregion
+
+// region Header
+func TestHeader_Add(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.Target = "header"
+	cfg.ParamName = "x-new-header"
+	cfg.NewValue = "newvalue"
+	previous := http.Header{"Accept": {"*/*"}}
+	expected := http.Header{"Accept": {"*/*"}, "X-New-Header": {"newvalue"}}
+
+	assertHeaderModification(t, cfg, previous, expected)
+}
+
+func TestHeader_DeleteNameRegex(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "header"
+	cfg.ParamNameRegex = "^x-debug-"
+	previous := http.Header{"Accept": {"*/*"}, "X-Debug-Id": {"1"}, "X-Debug-Trace": {"2"}}
+	expected := http.Header{"Accept": {"*/*"}}
+
+	assertHeaderModification(t, cfg, previous, expected)
+}
+
+func TestHeader_DeleteNameCaseInsensitive(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "header"
+	cfg.ParamName = "x-debug"
+	previous := http.Header{"Accept": {"*/*"}, "X-Debug": {"1"}}
+	expected := http.Header{"Accept": {"*/*"}}
+
+	assertHeaderModification(t, cfg, previous, expected)
+}
+
+func TestHeader_ModifyValueRegex(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.Target = "header"
+	cfg.ParamValueRegex = "^Bearer (.*)$"
+	cfg.NewValueRegex = "Token $1"
+	previous := http.Header{"Accept": {"*/*"}, "Authorization": {"Bearer abc"}}
+	expected := http.Header{"Accept": {"*/*"}, "Authorization": {"Token abc"}}
+
+	assertHeaderModification(t, cfg, previous, expected)
+}
+
+func TestHeader_QueryUntouched(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "header"
+	cfg.ParamName = "a"
+	previous := "a=b"
+	expected := "a=b"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestHeader_QueryAndHeaderTargets(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Targets = []string{"query", "header"}
+	cfg.ParamName = "x-tracking"
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req.URL.RawQuery = "x-tracking=1&X-Tracking=2&a=1"
+	req.Header.Set("X-Tracking", "1")
+	req.Header.Set("Accept", "*/*")
+	handler.ServeHTTP(recorder, req)
+
+	// the query is matched case-sensitively, headers never are
+	if expected := "X-Tracking=2&a=1"; req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+	if expected := (http.Header{"Accept": {"*/*"}}); !reflect.DeepEqual(req.Header, expected) {
+		t.Errorf("Expected %v, got %v", expected, req.Header)
+	}
+}
+
+func TestHeader_ErrorTargetAndTargets(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "query"
+	cfg.Targets = []string{"header"}
+	cfg.ParamName = "a"
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+// endregion
+
+// region Copy To Header
+func TestCopyToHeader(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "copy-to-header"
+	cfg.ParamName = "token"
+	cfg.HeaderName = "X-Auth-Token"
+
+	req := assertCopyToHeader(t, cfg, "token=abc&token=def&other=1", "X-Auth-Token", "abc")
+	if req.URL.Query().Encode() != "other=1&token=abc&token=def" {
+		t.Errorf("Expected %s, got %s", "other=1&token=abc&token=def", req.URL.Query().Encode())
+	}
+}
+
+func TestCopyToHeader_RemoveParam(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "copy-to-header"
+	cfg.ParamName = "token"
+	cfg.HeaderName = "X-Auth-Token"
+	cfg.RemoveParam = true
+
+	req := assertCopyToHeader(t, cfg, "token=abc&other=1", "X-Auth-Token", "abc")
+	if req.URL.Query().Encode() != "other=1" {
+		t.Errorf("Expected %s, got %s", "other=1", req.URL.Query().Encode())
+	}
+}
+
+func TestCopyToHeader_JoinValues(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "copy-to-header"
+	cfg.ParamName = "tenant"
+	cfg.JoinValues = true
+
+	assertCopyToHeader(t, cfg, "tenant=a&tenant=b", "Tenant", "a, b")
+}
+
+func TestCopyToHeader_Absent(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "copy-to-header"
+	cfg.ParamName = "token"
+
+	req := assertCopyToHeader(t, cfg, "other=1", "Token", "")
+	if _, ok := req.Header["Token"]; ok {
+		t.Error("Expected no header to be set")
+	}
+}
+
+// endregion
+
+// region Rules
+func TestRules_AddThenModify(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "add", ParamName: "newparam", NewValue: "newvalue"},
+		{Type: "modify", ParamName: "newparam", NewValue: "modified-$1"},
+	}
+	previous := "a=b"
+	expected := "a=b&newparam=modified-newvalue"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestRules_TopLevelFirst(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "add", ParamName: "a", NewValue: "c"},
+	}
+	previous := "a=b"
+	expected := "a=c"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestRules_QueryAndHeader(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "delete", ParamName: "a"},
+		{Type: "add", Target: "header", ParamName: "x-a", NewValue: "removed"},
+	}
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.URL.RawQuery = "a=b&c=d"
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.RawQuery != "c=d" {
+		t.Errorf("Expected %s, got %s", "c=d", req.URL.RawQuery)
+	}
+	if req.Header.Get("X-A") != "removed" {
+		t.Errorf("Expected %s, got %s", "removed", req.Header.Get("X-A"))
+	}
+}
+
+func TestErrorInvalidRule(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "add", ParamName: "a", NewValue: "b"},
+		{Type: "delete"},
+	}
+	ctx := context.Background()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+// endregion
+
+// region Path
+func TestPath_Matching(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.PathRegex = "^/api/v1/"
+
+	assertQueryModificationWithURL(t, cfg, "http://localhost/api/v1/users?a=b&c=d", "c=d")
+}
+
+func TestPath_NotMatching(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.PathRegex = "^/api/v1/"
+
+	assertQueryModificationWithURL(t, cfg, "http://localhost/api/v2/users?a=b&c=d", "a=b&c=d")
+}
+
+func TestPath_ForwardedURI(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.PathRegex = "^/api/v1/"
+	cfg.PathSource = "forwarded-uri"
+
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.URL.Path = "/auth"
+	req.URL.RawQuery = "a=b&c=d"
+	req.Header.Set("X-Forwarded-Uri", "/api/v1/users?a=b")
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.RawQuery != "c=d" {
+		t.Errorf("Expected c=d, got %s", req.URL.RawQuery)
+	}
+}
+
+func TestPath_ForwardedURINotMatching(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.PathRegex = "^/api/v1/"
+	cfg.PathSource = "forwarded-uri"
+
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the request path matches, but only the forwarded URI is considered
+	req.URL.Path = "/api/v1/users"
+	req.URL.RawQuery = "a=b&c=d"
+	req.Header.Set("X-Forwarded-Uri", "/api/v2/users")
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.RawQuery != "a=b&c=d" {
+		t.Errorf("Expected a=b&c=d, got %s", req.URL.RawQuery)
+	}
+}
+
+func TestPath_ForwardedURIMissing(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.PathRegex = "^/"
+	cfg.PathSource = "forwarded-uri"
+
+	assertQueryModificationWithURL(t, cfg, "http://localhost/api/v1/users?a=b&c=d", "a=b&c=d")
+}
+
+func TestPath_ExplicitRequestPath(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.PathRegex = "^/api/v1/"
+	cfg.PathSource = "path"
+
+	assertQueryModificationWithURL(t, cfg, "http://localhost/api/v1/users?a=b&c=d", "c=d")
+}
+
+func TestErrorInvalidPathRegex(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.PathRegex = "^/api/(v1"
+	ctx := context.Background()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+// endregion
+
+// region Max Params
+func TestMaxParams_Exceeded(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.MaxParams = 10
+	var params []string
+	for i := 0; i < 100; i++ {
+		params = append(params, fmt.Sprintf("p%d=%d", i, i))
+	}
+	previous := "a=b&" + strings.Join(params, "&")
+
+	assertRawQueryModification(t, cfg, previous, previous)
+}
+
+func TestMaxParams_NotExceeded(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.MaxParams = 2
+	previous := "a=b&c=d&c=e"
+	expected := "c=d&c=e"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestMaxParams_Rejected(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "token"
+	cfg.MaxParams = 2
+	cfg.RejectExcessParams = true
+
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.URL.RawQuery = "token=secret&a=1&b=2"
+	handler.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+
+	assertQueryModification(t, cfg, "token=secret&a=1", "a=1")
+}
+
+func TestMaxParams_RejectRequiresMaxParams(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.RejectExcessParams = true
+
+	err := traefik_plugin_parameters.ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "rejectExcessParams") {
+		t.Errorf("expected an error about rejectExcessParams, got %v", err)
+	}
+}
+
+// endregion
+
+// region Cancelled Context
+func TestCancelledContext_ForwardedUnchanged(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+
+	forwarded := false
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		forwarded = true
+		if req.URL.RawQuery != "a=b&c=d" {
+			t.Errorf("Expected unchanged query, got %s", req.URL.RawQuery)
+		}
+	})
+	handler := newHandler(t, cfg, next)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/?a=b&c=d", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !forwarded {
+		t.Error("Expected the request to be forwarded")
+	}
+}
+
+// endregion
+
+// region Sampling
+func TestSamplePercent_Approximate(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "sampled"
+	cfg.NewValue = "true"
+	cfg.SamplePercent = 5
+
+	modified := 0
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("sampled") == "true" {
+			modified++
+		}
+	})
+	handler := newHandler(t, cfg, next)
+	handler.SetRandomSeed(1)
+
+	const requests = 10000
+	for i := 0; i < requests; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost?a=b", nil))
+	}
+
+	if modified < requests*4/100 || modified > requests*6/100 {
+		t.Errorf("Expected about 5%% of %d requests to be modified, got %d", requests, modified)
+	}
+}
+
+func TestSamplePercent_UnsetModifiesAll(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "sampled"
+	cfg.NewValue = "true"
+
+	for i := 0; i < 100; i++ {
+		assertQueryModification(t, cfg, "a=b", "a=b&sampled=true")
+	}
+}
+
+func TestSamplePercent_ErrorOutOfRange(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.SamplePercent = 101
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+// endregion
+
+// region Concurrency
+func TestServeHTTP_ConcurrentRequests(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "modify", ParamNameRegex: "^(id|ref)$", ParamValueRegex: `^(\d+)$`, NewValueRegex: "n$1"},
+		{Type: "delete", ParamValueRegex: "^secret$"},
+		{Type: "add", ParamName: "seq", NewValueTemplate: "{{.Counter}}"},
+		{Type: "add", ParamName: "X-Seen", NewValue: "1", Target: "header"},
+		{Type: "modify-or-add", ParamName: "tag", ParamValueIn: []string{"b"}, NewValue: "B"},
+	}
+	cfg.NameCase = "lower"
+	cfg.Dedupe = true
+	cfg.SortValues = true
+	cfg.LogLevel = "none"
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		qry := req.URL.Query()
+		if qry.Get("id") != "n42" || qry.Get("token") != "" || qry.Get("seq") == "" || !reflect.DeepEqual(qry["tag"], []string{"B", "a"}) {
+			t.Errorf("unexpected query %s", req.URL.RawQuery)
+		}
+		if req.Header.Get("X-Seen") != "1" {
+			t.Errorf("expected header X-Seen to be added")
+		}
+	})
+	handler := newHandler(t, cfg, next)
+
+	const workers, requestsPerWorker = 16, 50
+	done := make(chan struct{})
+	go func() {
+		// changes to the configuration after New must not affect nor race with the handler
+		for i := 0; i < workers*requestsPerWorker; i++ {
+			cfg.Rules[0].NewValueRegex = fmt.Sprintf("changed%d", i)
+			cfg.Dedupe = i%2 == 0
+		}
+		close(done)
+	}()
+
+	finished := make(chan struct{}, workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := 0; i < requestsPerWorker; i++ {
+				req := httptest.NewRequest(http.MethodGet, "http://localhost/?ID=42&token=secret&tag=a&tag=b&tag=a", nil)
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+			finished <- struct{}{}
+		}()
+	}
+	for w := 0; w < workers; w++ {
+		<-finished
+	}
+	<-done
+}
+
+// endregion
+
+// region Fragment
+func TestFragment_Preserved(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	handler, err, recorder, _ := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://localhost/path?a=1&b=2#section", nil)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.Fragment != "section" {
+		t.Errorf("Expected fragment section, got %s", req.URL.Fragment)
+	}
+	if req.RequestURI != "/path?b=2" {
+		t.Errorf("Expected request URI /path?b=2, got %s", req.RequestURI)
+	}
+	if expected := "http://localhost/path?b=2#section"; req.URL.String() != expected {
+		t.Errorf("Expected URL %s, got %s", expected, req.URL.String())
+	}
+}
+
+// endregion
+
+// region Malformed Query
+func TestMalformedQuery_InvalidEscape(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "b"
+	previous := "a=%zz&b=1"
+
+	assertRawQueryModification(t, cfg, previous, previous)
+}
+
+func TestMalformedQuery_Semicolon(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "c"
+	cfg.NewValue = "d"
+	previous := "a=1;b=2"
+
+	assertRawQueryModification(t, cfg, previous, previous)
+}
+
+func TestMalformedQuery_Rejected(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "authenticated"
+	cfg.NewValue = "false"
+	cfg.RejectMalformed = true
+
+	for _, previous := range []string{"authenticated=true&x=%zz", "authenticated=true;x=1"} {
+		handler, err, recorder, req := createReqAndRecorder(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.URL.RawQuery = previous
+		handler.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, previous, recorder.Code)
+		}
+	}
+
+	// well-formed queries are modified as usual
+	assertQueryModification(t, cfg, "authenticated=true", "authenticated=false")
+}
+
+func TestMalformedQuery_RejectedDryRun(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "b"
+	cfg.RejectMalformed = true
+	cfg.DryRun = true
+
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.URL.RawQuery = "a=%zz&b=1"
+	handler.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK || req.URL.RawQuery != "a=%zz&b=1" {
+		t.Errorf("Expected the request to be forwarded unchanged in dry run mode, got status %d and query %s", recorder.Code, req.URL.RawQuery)
+	}
+}
+
+// endregion
+
+// region Semicolon Separator
+func TestSemicolonSeparator_RoundTrip(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "b"
+	cfg.SemicolonSeparator = true
+	previous := "a=1;b=2;c=3"
+	expected := "a=1;c=3"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestSemicolonSeparator_PreserveOrder(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "a"
+	cfg.NewValue = "x;y"
+	cfg.SemicolonSeparator = true
+	cfg.PreserveOrder = true
+	previous := "z=1&b=2;a=3"
+	expected := "z=1;b=2;a=3;a=x%3By"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestSemicolonSeparator_UnmodifiedQueryUntouched(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "header"
+	cfg.ParamName = "X-A"
+	cfg.SemicolonSeparator = true
+	previous := "b=2;a=1"
+
+	assertRawQueryModification(t, cfg, previous, previous)
+}
+
+// endregion
+
+// region Collapse Repeated
+func TestCollapseRepeated_AllParams(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.CollapseRepeated = true
+	previous := "tag=a&tag=b&tag=c&id=1&x=1&x=2"
+	expected := "id=1&tag=a%2Cb%2Cc&x=1%2C2"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestCollapseRepeated_NamedParamWithSeparator(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "tag"
+	cfg.NewValue = "c"
+	cfg.CollapseRepeated = true
+	cfg.CollapseParams = []string{"tag"}
+	cfg.JoinSeparator = "|"
+	previous := "tag=a&tag=b&x=1&x=2"
+	expected := "tag=a%7Cb%7Cc&x=1&x=2"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestCollapseRepeated_ErrorSeparatorWithoutCollapse(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.JoinSeparator = "|"
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+// endregion
+
+// region Original Query
+func TestOriginalQueryParam_Recoverable(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "token"
+	cfg.OriginalQueryParam = "orig_q"
+	previous := "token=s%20ecret&b=1&b=2&orig_q=forged%3Bx"
+
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.URL.RawQuery = previous
+	handler.ServeHTTP(recorder, req)
+
+	qry := req.URL.Query()
+	if qry.Get("token") != "" {
+		t.Errorf("Expected token to be deleted, got %s", req.URL.RawQuery)
+	}
+	if original := qry["orig_q"]; len(original) != 1 || original[0] != previous {
+		t.Errorf("Expected original query %s, got %v", previous, original)
+	}
+}
+
+func TestOriginalQueryParam_WithoutRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.OriginalQueryParam = "orig_q"
+	previous := "a=1&b=%2F"
+	expected := "a=1&b=%2F&orig_q=a%3D1%26b%3D%252F"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+// endregion
+
+// region Dedupe
+func TestDedupe_AfterRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "tag"
+	cfg.NewValue = "a"
+	cfg.Dedupe = true
+	previous := "tag=b&tag=a&tag=c&tag=b"
+	expected := "tag=b&tag=a&tag=c"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestDedupe_WithoutModification(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "other"
+	cfg.Dedupe = true
+	previous := "tag=a&tag=a&tag=b&x=1&x=1"
+	expected := "tag=a&tag=b&x=1"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestDedupe_NamedParam(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Dedupe = true
+	cfg.DedupeParams = []string{"tag"}
+	previous := "tag=a&tag=a&tag=b&x=1&x=1"
+	expected := "tag=a&tag=b&x=1&x=1"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestDedupe_WithoutRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Dedupe = true
+	previous := "tag=b&tag=a&tag=b"
+	expected := "tag=b&tag=a"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestDedupe_ErrorParamsWithoutDedupe(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.DedupeParams = []string{"tag"}
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil || !strings.Contains(err.Error(), "dedupeParams") {
+		t.Errorf("expected an error about dedupeParams, got %v", err)
+	}
+}
+
+// endregion
+
+// region Sort Values
+func TestSortValues_NamedParam(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.SortValues = true
+	cfg.SortParams = []string{"tags"}
+	previous := "tags=c&x=2&tags=a&x=1&tags=b"
+	expected := "tags=a&tags=b&tags=c&x=2&x=1"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestSortValues_AllParams(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.SortValues = true
+	previous := "tags=c&x=2&tags=a&x=1"
+	expected := "tags=a&tags=c&x=1&x=2"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestSortValues_WithDedupe(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "tags"
+	cfg.NewValue = "a"
+	cfg.SortValues = true
+	cfg.SortParams = []string{"tags"}
+	cfg.Dedupe = true
+	previous := "tags=c&tags=b&tags=c&id=1"
+	expected := "id=1&tags=a&tags=b&tags=c"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestSortValues_ErrorParamsWithoutSort(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.SortParams = []string{"tags"}
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil || !strings.Contains(err.Error(), "sortParams") {
+		t.Errorf("expected an error about sortParams, got %v", err)
+	}
+}
+
+// endregion
+
+// region Numeric Comparison
+func TestNumericComparison_GreaterThan(t *testing.T) {
+	bound := 1000
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "page"
+	cfg.ParamValueGreaterThan = &bound
+	previous := "page=1001&q=go"
+	expected := "q=go"
+
+	assertQueryModification(t, cfg, previous, expected)
+
+	// the bound itself does not match
+	assertQueryModification(t, cfg, "page=1000&q=go", "page=1000&q=go")
+}
+
+func TestNumericComparison_LessThan(t *testing.T) {
+	bound := 0
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamNameRegex = "^(offset|limit)$"
+	cfg.ParamValueLessThan = &bound
+	cfg.NewValue = "0"
+	previous := "limit=10&offset=-5"
+	expected := "limit=10&offset=0"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestNumericComparison_Range(t *testing.T) {
+	lower, upper := 1, 5
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamValueGreaterThan = &lower
+	cfg.ParamValueLessThan = &upper
+	previous := "a=3&b=5&c=1&d=2&d=9"
+	expected := "b=5&c=1&d=9"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestNumericComparison_NonNumeric(t *testing.T) {
+	bound := 1000
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "page"
+	cfg.ParamValueGreaterThan = &bound
+	previous := "page=last&page=1e9&page=2000.5&page=&q=go"
+	expected := "page=last&page=1e9&page=2000.5&page=&q=go"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+// endregion
+
+// region Debug Header
+func TestDebugHeader_ListsModifications(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "add", ParamName: "foo", NewValue: "1"},
+		{Type: "delete", ParamName: "bar"},
+	}
+	cfg.DebugHeaderName = "X-Query-Modified"
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req.URL.RawQuery = "bar=1"
+	handler.ServeHTTP(recorder, req)
+
+	if expected := "add=foo,delete=bar"; recorder.Header().Get("X-Query-Modified") != expected {
+		t.Errorf("Expected %s, got %s", expected, recorder.Header().Get("X-Query-Modified"))
+	}
+}
+
+func TestDebugHeader_DeterministicOrder(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameRegex = "^utm_"
+	cfg.DebugHeaderName = "X-Query-Modified"
+	expected := "delete=utm_a,delete=utm_b,delete=utm_c,delete=utm_d"
+
+	for i := 0; i < 20; i++ {
+		handler, err, recorder, req := createReqAndRecorder(cfg)
+		if err != nil {
+			t.Fatal(err)
+			return
+		}
+		req.URL.RawQuery = "utm_d=1&utm_b=1&utm_c=1&utm_a=1"
+		handler.ServeHTTP(recorder, req)
+
+		if recorder.Header().Get("X-Query-Modified") != expected {
+			t.Fatalf("Expected %s, got %s", expected, recorder.Header().Get("X-Query-Modified"))
+		}
+	}
+}
+
+func TestDebugHeader_AbsentWithoutModification(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "bar"
+	cfg.DebugHeaderName = "X-Query-Modified"
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req.URL.RawQuery = "foo=1"
+	handler.ServeHTTP(recorder, req)
+
+	if _, ok := recorder.Header()["X-Query-Modified"]; ok {
+		t.Error("Expected no debug header")
+	}
+}
+
+func TestMalformedQuery_ModifiedQueryInvalid(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "add", ParamName: "a", NewValue: "b"},
+		{Type: "add", Target: "header", ParamName: "X-Added", NewValue: "1"},
+	}
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	handler.(*traefik_plugin_parameters.QueryModification).SetQueryEncoder(func(rawQuery string, qry url.Values) string {
+		return qry.Encode() + "&broken=%zz"
+	})
+	req.URL.RawQuery = "c=d"
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.RawQuery != "c=d" {
+		t.Errorf("Expected original query c=d, got %s", req.URL.RawQuery)
+	}
+	if _, ok := req.Header["X-Added"]; ok {
+		t.Error("Expected headers to be left untouched")
+	}
+}
+
+// endregion
+
+// region Dry Run
+func TestDryRun_QueryUnchanged(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.NewValue = "c"
+	cfg.DryRun = true
+	var forwardedQuery string
+	ctx := context.Background()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		forwardedQuery = req.URL.RawQuery
+	})
+	handler := newHandler(t, cfg, next)
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost?a=b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if forwardedQuery != "a=b" {
+		t.Errorf("Expected %s, got %s", "a=b", forwardedQuery)
+	}
+	if !strings.Contains(logged.String(), `after="a=c"`) {
+		t.Errorf("Expected the modified query to be logged, got %s", logged.String())
+	}
+}
+
+func TestDryRun_HeaderUnchanged(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "header"
+	cfg.ParamName = "x-debug"
+	cfg.DryRun = true
+	previous := http.Header{"X-Debug": {"1"}}
+	expected := http.Header{"X-Debug": {"1"}}
+
+	assertHeaderModification(t, cfg, previous, expected)
+}
+
+// endregion
+
+func TestErrorInvalidType(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "bla"
+	cfg.ParamName = "blub"
+	ctx := context.Background()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+func TestErrorEmptyType(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.ParamName = "blub"
+	ctx := context.Background()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+
+	if err == nil || !strings.HasPrefix(err.Error(), "type must be set") {
+		t.Errorf("Expected error about the missing type, got %v", err)
+	}
+}
+
+func TestErrorEmptyTypeInRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "a"}, {ParamName: "b"}}
+	ctx := context.Background()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+
+	if err == nil || !strings.HasPrefix(err.Error(), "rules[1]: type must be set") {
+		t.Errorf("Expected error about the missing type, got %v", err)
+	}
+}
+
+func TestErrorInvalidTarget(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.Target = "cookie"
+	cfg.ParamName = "blub"
+	ctx := context.Background()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+func TestErrorValueOnly(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *traefik_plugin_parameters.Config)
+	}{
+		{"paramName", func(cfg *traefik_plugin_parameters.Config) { cfg.ParamName = "a" }},
+		{"paramNameRegex", func(cfg *traefik_plugin_parameters.Config) { cfg.ParamNameRegex = "^a$" }},
+		{"paramNameGlob", func(cfg *traefik_plugin_parameters.Config) { cfg.ParamNameGlob = "a*" }},
+		{"paramNamePrefix", func(cfg *traefik_plugin_parameters.Config) { cfg.ParamNamePrefix = "a" }},
+		{"paramNameSuffix", func(cfg *traefik_plugin_parameters.Config) { cfg.ParamNameSuffix = "a" }},
+		{"negateNameMatch", func(cfg *traefik_plugin_parameters.Config) { cfg.NegateNameMatch = true }},
+		{"no paramValueRegex", func(cfg *traefik_plugin_parameters.Config) { cfg.ParamValueRegex = "" }},
+		{"delete type", func(cfg *traefik_plugin_parameters.Config) { cfg.Type = "delete" }},
+		{"valueMap", func(cfg *traefik_plugin_parameters.Config) { cfg.ValueMap = map[string]string{"b": "c"} }},
+		{"newValueTemplate", func(cfg *traefik_plugin_parameters.Config) { cfg.NewValueTemplate = "{{.Value}}" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.Type = "modify"
+			cfg.ValueOnly = true
+			cfg.ParamValueRegex = "b"
+			cfg.NewValue = "c"
+			tt.modify(cfg)
+			ctx := context.Background()
+			next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+			_, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+
+			if err == nil {
+				t.Error("expected error but err is nil")
+			}
+		})
+	}
+}
+
+func TestErrorNoParam(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	ctx := context.Background()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "add", ParamName: "newparam", NewValue: "newvalue"},
+		{Type: "modify", ParamValueRegex: "^secret.*$", NewValue: "censored"},
+		{Type: "delete", ParamNameRegex: "^utm_"},
+	}
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	const rawQuery = "a=b&password=secretpassword&utm_source=x&utm_medium=y&c=d&c=e"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req.URL.RawQuery = rawQuery
+		handler.ServeHTTP(recorder, req)
+	}
+}
+
+func TestErrorPerTypeValidation(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		config        traefik_plugin_parameters.RuleConfig
+		expectedError string
+	}{
+		{desc: "add without paramName", config: traefik_plugin_parameters.RuleConfig{Type: "add", NewValue: "a"}, expectedError: "paramName"},
+		{desc: "add with regex only", config: traefik_plugin_parameters.RuleConfig{Type: "add", ParamNameRegex: "a", NewValue: "a"}, expectedError: "paramName"},
+		{desc: "add-or-replace without paramName", config: traefik_plugin_parameters.RuleConfig{Type: "add-or-replace", NewValue: "a"}, expectedError: "paramName"},
+		{desc: "add-if-absent without paramName", config: traefik_plugin_parameters.RuleConfig{Type: "add-if-absent", NewValue: "a"}, expectedError: "paramName"},
+		{desc: "rename without newName", config: traefik_plugin_parameters.RuleConfig{Type: "rename", ParamName: "a"}, expectedError: "newName"},
+		{desc: "rename without matcher", config: traefik_plugin_parameters.RuleConfig{Type: "rename", NewName: "a"}, expectedError: "paramNameRegex or paramName or paramValueRegex"},
+		{desc: "delete without matcher", config: traefik_plugin_parameters.RuleConfig{Type: "delete"}, expectedError: "paramNameRegex or paramName or paramValueRegex"},
+		{desc: "modify without matcher", config: traefik_plugin_parameters.RuleConfig{Type: "modify", NewValue: "a"}, expectedError: "paramNameRegex or paramName or paramValueRegex"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = test.config
+			next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+			_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+			if err == nil {
+				t.Fatal("expected error but err is nil")
+			}
+			if !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("Expected error containing %s, got %s", test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestErrorParamValueInWithRegex(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamValueIn = []string{"a"}
+	cfg.ParamValueRegex = "^a$"
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+func TestErrorMeaninglessCombinations(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		config        traefik_plugin_parameters.RuleConfig
+		expectedError string
+	}{
+		{desc: "delete with newValue", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", NewValue: "b"}, expectedError: "no effect for type delete"},
+		{desc: "delete with newValueRegex", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamValueRegex: "a", NewValueRegex: "b"}, expectedError: "no effect for type delete"},
+		{desc: "add with paramValueRegex", config: traefik_plugin_parameters.RuleConfig{Type: "add", ParamName: "a", ParamValueRegex: "b"}, expectedError: "no effect for type add"},
+		{desc: "add-if-absent with paramNameRegex", config: traefik_plugin_parameters.RuleConfig{Type: "add-if-absent", ParamName: "a", ParamNameRegex: "b"}, expectedError: "no effect for type add-if-absent"},
+		{desc: "rename with newValue", config: traefik_plugin_parameters.RuleConfig{Type: "rename", ParamName: "a", NewName: "b", NewValue: "c"}, expectedError: "no effect for type rename"},
+		{desc: "copy-to-header with newValue", config: traefik_plugin_parameters.RuleConfig{Type: "copy-to-header", ParamName: "a", NewValue: "c"}, expectedError: "no effect for type copy-to-header"},
+		{desc: "modify with newName", config: traefik_plugin_parameters.RuleConfig{Type: "modify", ParamName: "a", NewName: "b"}, expectedError: "newName"},
+		{desc: "modify with valueFromHeader", config: traefik_plugin_parameters.RuleConfig{Type: "modify", ParamName: "a", NewValue: "b", ValueFromHeader: "X-A"}, expectedError: "valueFromHeader"},
+		{desc: "modify with newValues", config: traefik_plugin_parameters.RuleConfig{Type: "modify", ParamName: "a", NewValues: []string{"b"}}, expectedError: "newValues"},
+		{desc: "clear with paramName", config: traefik_plugin_parameters.RuleConfig{Type: "clear", ParamName: "a"}, expectedError: "no effect for type clear"},
+		{desc: "delete with valueFromEnv", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", ValueFromEnv: "HOME"}, expectedError: "valueFromEnv"},
+		{desc: "valueFromEnv with newValue", config: traefik_plugin_parameters.RuleConfig{Type: "add", ParamName: "a", NewValue: "b", ValueFromEnv: "HOME"}, expectedError: "valueFromEnv cannot be used together"},
+		{desc: "delete with headerName", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", HeaderName: "b"}, expectedError: "headerName"},
+		{desc: "add with paramValueGreaterThan", config: traefik_plugin_parameters.RuleConfig{Type: "add", ParamName: "a", ParamValueGreaterThan: new(int)}, expectedError: "no effect for type add"},
+		{desc: "delete with valueMap", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", ValueMap: map[string]string{"b": "c"}}, expectedError: "valueMap can only be used with type modify"},
+		{desc: "valueMap with newValue", config: traefik_plugin_parameters.RuleConfig{Type: "modify", ParamName: "a", NewValue: "b", ValueMap: map[string]string{"b": "c"}}, expectedError: "valueMap cannot be used together"},
+		{desc: "deleteUnmapped without valueMap", config: traefik_plugin_parameters.RuleConfig{Type: "modify", ParamName: "a", NewValue: "b", DeleteUnmapped: true}, expectedError: "deleteUnmapped"},
+		{desc: "add with negateNameMatch", config: traefik_plugin_parameters.RuleConfig{Type: "add", ParamName: "a", NegateNameMatch: true}, expectedError: "no effect for type add"},
+		{desc: "negateNameMatch without name matcher", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamValueRegex: "a", NegateNameMatch: true}, expectedError: "negateNameMatch requires a name matcher"},
+		{desc: "negateValueMatch without value matcher", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", NegateValueMatch: true}, expectedError: "negateValueMatch requires a value matcher"},
+		{desc: "valueIndex with delete", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", ValueIndex: new(int)}, expectedError: "valueIndex can only be used with the modify type"},
+		{desc: "valueIndex with matchFirstOnly", config: traefik_plugin_parameters.RuleConfig{Type: "modify", ParamName: "a", NewValue: "b", ValueIndex: new(int), MatchFirstOnly: true}, expectedError: "valueIndex and matchFirstOnly cannot be used together"},
+		{desc: "empty numeric range", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamValueGreaterThan: new(int), ParamValueLessThan: new(int)}, expectedError: "paramValueGreaterThan must be less than paramValueLessThan"},
+		{desc: "pathSource without pathRegex", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", PathSource: "forwarded-uri"}, expectedError: "pathSource can only be used together with pathRegex"},
+		{desc: "invalid pathSource", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", PathRegex: "^/", PathSource: "header"}, expectedError: "invalid pathSource"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = test.config
+			next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+			_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+			if err == nil {
+				t.Fatal("expected error but err is nil")
+			}
+			if !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("Expected error containing %s, got %s", test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		config        traefik_plugin_parameters.Config
+		expectedError string
+	}{
+		{desc: "valid", config: traefik_plugin_parameters.Config{RuleConfig: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a"}}},
+		{desc: "valid rules", config: traefik_plugin_parameters.Config{Rules: []traefik_plugin_parameters.RuleConfig{{Type: "add", ParamName: "a", NewValue: "b"}}}},
+		{desc: "invalid log level", config: traefik_plugin_parameters.Config{LogLevel: "info", RuleConfig: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a"}}, expectedError: "invalid log level"},
+		{desc: "invalid type", config: traefik_plugin_parameters.Config{RuleConfig: traefik_plugin_parameters.RuleConfig{Type: "unknown", ParamName: "a"}}, expectedError: "invalid modification type"},
+		{desc: "missing matcher", config: traefik_plugin_parameters.Config{RuleConfig: traefik_plugin_parameters.RuleConfig{Type: "delete"}}, expectedError: "must be set for type"},
+		{desc: "invalid regex", config: traefik_plugin_parameters.Config{RuleConfig: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamNameRegex: "("}}, expectedError: "paramNameRegex"},
+		{desc: "cross-field constraint", config: traefik_plugin_parameters.Config{RuleConfig: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", NewValue: "b"}}, expectedError: "no effect for type delete"},
+		{desc: "invalid rule", config: traefik_plugin_parameters.Config{Rules: []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "a"}, {Type: "add"}}}, expectedError: "rules[1]"},
+		{desc: "missing signature secret", config: traefik_plugin_parameters.Config{VerifySignature: true}, expectedError: "signatureSecret"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			err := traefik_plugin_parameters.ValidateConfig(&test.config)
+
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("Expected error containing %s, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestStrictMatchers_Error(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.ParamValueRegex = "^b$"
+	cfg.NewValue = "c"
+	cfg.StrictMatchers = true
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil || !strings.Contains(err.Error(), "strictMatchers") {
+		t.Errorf("Expected error about strictMatchers, got %v", err)
+	}
+}
+
+func TestStrictMatchers_ErrorInRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.StrictMatchers = true
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "delete", ParamName: "a"},
+		{Type: "delete", ParamName: "b", ParamNameRegex: "^c$"},
+	}
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil || !strings.Contains(err.Error(), "rules[1]") {
+		t.Errorf("Expected error for rules[1], got %v", err)
+	}
+}
+
+func TestStrictMatchers_DisabledByDefault(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.ParamValueRegex = "^b$"
+	cfg.NewValue = "c"
+	previous := "a=b"
+	expected := "a=c"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestErrorInvalidRegexNamesField(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		config        traefik_plugin_parameters.RuleConfig
+		expectedError string
+	}{
+		{desc: "paramNameRegex", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamNameRegex: "(?<=a)b"}, expectedError: "paramNameRegex: "},
+		{desc: "paramValueRegex", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamValueRegex: "a++"}, expectedError: "paramValueRegex: "},
+		{desc: "pathRegex", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", PathRegex: "(a"}, expectedError: "pathRegex: "},
+		{desc: "schemeRegex", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", SchemeRegex: "[a"}, expectedError: "schemeRegex: "},
+		{desc: "conditionValueRegex", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a", ConditionParam: "b", ConditionValueRegex: `(\1)`}, expectedError: "conditionValueRegex: "},
+		{desc: "over-length", config: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamValueRegex: strings.Repeat("a", 1025)}, expectedError: "paramValueRegex: regex exceeds the maximum length of 1024"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = test.config
+			next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+			_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+			if err == nil {
+				t.Fatal("expected error but err is nil")
+			}
+			if !strings.HasPrefix(err.Error(), test.expectedError) {
+				t.Errorf("Expected error starting with %s, got %s", test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestMaxRegexLength_Configured(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameRegex = "^abcdef$"
+	cfg.MaxRegexLength = 4
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+	if err == nil || !strings.Contains(err.Error(), "maximum length of 4") {
+		t.Errorf("Expected error about the maximum length, got %v", err)
+	}
+
+	cfg.MaxRegexLength = 8
+	_, err = traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func createReqAndRecorder(cfg *traefik_plugin_parameters.Config) (http.Handler, error, *httptest.ResponseRecorder, *http.Request) {
+	return createReqAndRecorderWithMethod(cfg, http.MethodGet)
+}
+
+func createReqAndRecorderWithMethod(cfg *traefik_plugin_parameters.Config, method string) (http.Handler, error, *httptest.ResponseRecorder, *http.Request) {
+	ctx := context.Background()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	handler, err := traefik_plugin_parameters.New(ctx, next, cfg, "query-modification-plugin")
+	if err != nil {
+		return nil, err, nil, nil
+	}
+
+	recorder := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(ctx, method, "http://localhost", nil)
+	return handler, err, recorder, req
+}
+
+// newHandler creates the plugin for the given configuration, passing requests to the given next handler.
+// A nil next handler does nothing.
+func newHandler(t *testing.T, cfg *traefik_plugin_parameters.Config, next http.Handler) *traefik_plugin_parameters.QueryModification {
+	t.Helper()
+
+	if next == nil {
+		next = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	}
+	handler, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler.(*traefik_plugin_parameters.QueryModification)
+}
+
+// serve serves a request with the given method and target to the given handler and returns the response
+// and the request as passed to the handler, which is modified by the plugin.
+func serve(handler http.Handler, method, target string) (*httptest.ResponseRecorder, *http.Request) {
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, nil)
+	handler.ServeHTTP(recorder, req)
+	return recorder, req
+}
+
+func assertQueryModification(t *testing.T, cfg *traefik_plugin_parameters.Config, previous, expected string) {
+	assertQueryModificationWithMethod(t, cfg, http.MethodGet, previous, expected)
+}
+
+func assertQueryModificationWithMethod(t *testing.T, cfg *traefik_plugin_parameters.Config, method, previous, expected string) {
+	handler, err, recorder, req := createReqAndRecorderWithMethod(cfg, method)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req.URL.RawQuery = previous
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+}
+
+func assertHeaderModification(t *testing.T, cfg *traefik_plugin_parameters.Config, previous, expected http.Header) {
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req.Header = previous
+	handler.ServeHTTP(recorder, req)
+
+	if !reflect.DeepEqual(req.Header, expected) {
+		t.Errorf("Expected %v, got %v", expected, req.Header)
+	}
+}
+
+func assertQueryModificationWithURL(t *testing.T, cfg *traefik_plugin_parameters.Config, rawURL, expected string) {
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req.URL, err = url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+}
+
+func assertCopyToHeader(t *testing.T, cfg *traefik_plugin_parameters.Config, previous, headerName, expected string) *http.Request {
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return nil
+	}
+	req.URL.RawQuery = previous
+	handler.ServeHTTP(recorder, req)
+
+	if req.Header.Get(headerName) != expected {
+		t.Errorf("Expected header %s, got %s", expected, req.Header.Get(headerName))
 	}
+	return req
 }
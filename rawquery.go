@@ -0,0 +1,249 @@
+package traefik_plugin_parameters
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// spaceEncoding selects how spaces are encoded in modified queries
+type spaceEncoding string
+
+const (
+	plusSpaceEncoding    spaceEncoding = "plus"
+	percentSpaceEncoding spaceEncoding = "percent"
+)
+
+func (e spaceEncoding) isValid() bool {
+	switch e {
+	case plusSpaceEncoding, percentSpaceEncoding, "":
+		return true
+	}
+
+	return false
+}
+
+// escaper returns the function escaping names and values of re-encoded params with the given space encoding
+func (e spaceEncoding) escaper() func(string) string {
+	if e != percentSpaceEncoding {
+		return url.QueryEscape
+	}
+	// plus signs within names and values are encoded as %2B, so every remaining plus sign is a space
+	return func(s string) string { return strings.ReplaceAll(url.QueryEscape(s), "+", "%20") }
+}
+
+// encodeOrdered encodes the given values like url.Values.Encode, but keeps the order of the params in the original raw query.
+// Every value takes the position of the original value with the same key and index,
+// values without such an original position (e.g. added params) are appended sorted by key.
+// A param contained in replaced takes the position of the removed param mapped to it.
+// Names and values are escaped with the given function.
+func encodeOrdered(rawQuery string, values url.Values, replaced map[string]string, escape func(string) string) string {
+	return encodeRaw(rawQuery, values, replaced, nil, escape)
+}
+
+// encodePreserving encodes the given values like encodeOrdered, but only re-encodes the params whose values differ
+// from the original ones. The segments of all other params are passed through byte-for-byte.
+func encodePreserving(rawQuery string, original, values url.Values, replaced map[string]string, escape func(string) string) string {
+	modified := make(map[string]bool)
+	for key, vs := range values {
+		if !reflect.DeepEqual(vs, original[key]) {
+			modified[key] = true
+		}
+	}
+	for key := range original {
+		if _, ok := values[key]; !ok {
+			modified[key] = true
+		}
+	}
+	return encodeRaw(rawQuery, values, replaced, modified, escape)
+}
+
+// encodeRaw walks the tokens of the raw query and writes the values in their original position.
+// If modified is not nil, tokens of params not contained in it are copied unchanged.
+func encodeRaw(rawQuery string, values url.Values, replaced map[string]string, modified map[string]bool, escape func(string) string) string {
+	remaining := make(map[string][]string, len(values))
+	for key, vs := range values {
+		remaining[key] = vs
+	}
+
+	var sb strings.Builder
+	for _, token := range strings.Split(rawQuery, "&") {
+		if token == "" {
+			continue
+		}
+
+		rawKey := token
+		if i := strings.Index(token, "="); i >= 0 {
+			rawKey = token[:i]
+		}
+		key, err := url.QueryUnescape(rawKey)
+		if modified != nil && (err != nil || !modified[key]) {
+			writeRaw(&sb, token)
+			if err == nil && len(remaining[key]) > 0 {
+				remaining[key] = remaining[key][1:]
+			}
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if _, ok := values[key]; !ok && replaced[key] != "" {
+			key = replaced[key]
+		}
+		vs := remaining[key]
+		if len(vs) == 0 {
+			// the param was deleted or all its values have already been written
+			continue
+		}
+		writeParam(&sb, key, vs[0], escape)
+		remaining[key] = vs[1:]
+	}
+
+	keys := make([]string, 0, len(remaining))
+	for key, vs := range remaining {
+		if len(vs) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range remaining[key] {
+			writeParam(&sb, key, value, escape)
+		}
+	}
+
+	return sb.String()
+}
+
+// rawNames maps the decoded names of the params in the raw query to their distinct forms in the raw query
+func rawNames(rawQuery string) map[string][]string {
+	names := make(map[string][]string)
+	for _, token := range strings.Split(rawQuery, "&") {
+		if token == "" {
+			continue
+		}
+
+		rawKey := token
+		if i := strings.Index(token, "="); i >= 0 {
+			rawKey = token[:i]
+		}
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			continue
+		}
+
+		known := false
+		for _, name := range names[key] {
+			known = known || name == rawKey
+		}
+		if !known {
+			names[key] = append(names[key], rawKey)
+		}
+	}
+	return names
+}
+
+// rawValues maps the decoded values of the params in the raw query to their distinct forms in the raw query
+func rawValues(rawQuery string) map[string][]string {
+	values := make(map[string][]string)
+	for _, token := range strings.Split(rawQuery, "&") {
+		i := strings.Index(token, "=")
+		if i < 0 {
+			continue
+		}
+
+		rawValue := token[i+1:]
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			continue
+		}
+
+		known := false
+		for _, form := range values[value] {
+			known = known || form == rawValue
+		}
+		if !known {
+			values[value] = append(values[value], rawValue)
+		}
+	}
+	return values
+}
+
+// reservedQueryChars cannot be kept literal with literalChars, as they would change the structure of the query or its decoding
+const reservedQueryChars = "&=%+#;"
+
+// validLiteralChars reports whether the given literalChars only contain printable ASCII characters which are not reserved
+func validLiteralChars(chars string) bool {
+	for i := 0; i < len(chars); i++ {
+		if chars[i] <= ' ' || chars[i] > '~' || strings.IndexByte(reservedQueryChars, chars[i]) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// literalCharsReplacer returns a replacer decoding the percent-encoded forms of the given characters in encoded queries,
+// in upper and lower case, so encoded forms passed through from the raw query are decoded as well
+func literalCharsReplacer(chars string) *strings.Replacer {
+	pairs := make([]string, 0, 4*len(chars))
+	for i := 0; i < len(chars); i++ {
+		literal := chars[i : i+1]
+		pairs = append(pairs, fmt.Sprintf("%%%02X", chars[i]), literal, fmt.Sprintf("%%%02x", chars[i]), literal)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// emptyForm is a set of the forms an empty value takes in the raw query
+type emptyForm int
+
+const (
+	// emptyWithEquals is the form of ?debug=
+	emptyWithEquals emptyForm = 1 << iota
+	// emptyWithoutEquals is the form of ?debug
+	emptyWithoutEquals
+)
+
+// rawEmptyForms maps the decoded names of the params with empty values in the raw query to the forms of these values
+func rawEmptyForms(rawQuery string) map[string]emptyForm {
+	forms := make(map[string]emptyForm)
+	for _, token := range strings.Split(rawQuery, "&") {
+		if token == "" {
+			continue
+		}
+
+		rawKey, form := token, emptyWithoutEquals
+		if i := strings.Index(token, "="); i >= 0 {
+			if i < len(token)-1 {
+				continue
+			}
+			rawKey, form = token[:i], emptyWithEquals
+		}
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			continue
+		}
+		forms[key] |= form
+	}
+	return forms
+}
+
+// writeRaw appends the unchanged token to the given query builder
+func writeRaw(sb *strings.Builder, token string) {
+	if sb.Len() > 0 {
+		sb.WriteByte('&')
+	}
+	sb.WriteString(token)
+}
+
+// writeParam appends the key value pair escaped with the given function to the given query builder
+func writeParam(sb *strings.Builder, key, value string, escape func(string) string) {
+	if sb.Len() > 0 {
+		sb.WriteByte('&')
+	}
+	sb.WriteString(escape(key))
+	sb.WriteByte('=')
+	sb.WriteString(escape(value))
+}
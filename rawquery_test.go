@@ -0,0 +1,418 @@
+package traefik_plugin_parameters_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestPreserveOrder_Modify(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "b"
+	cfg.NewValue = "new-$1"
+	cfg.PreserveOrder = true
+	previous := "z=1&b=2&a=3&b=4"
+	expected := "z=1&b=new-2&a=3&b=new-4"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreserveOrder_Delete(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "b"
+	cfg.PreserveOrder = true
+	previous := "z=1&b=2&a=3&b=4&c=5"
+	expected := "z=1&a=3&c=5"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreserveOrder_AddAppends(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "a"
+	cfg.NewValue = "new"
+	cfg.PreserveOrder = true
+	previous := "z=1&a=2&b=3"
+	expected := "z=1&a=2&b=3&a=new"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreserveOrder_AddReplaceKeepsPosition(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "b"
+	cfg.NewValue = "new"
+	cfg.PreserveOrder = true
+	previous := "z=1&b=2&a=3&b=4"
+	expected := "z=1&b=new&a=3"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreserveOrder_AddReplaceKeepsPositionOfDifferentName(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "b"
+	cfg.NewValue = "new"
+	cfg.CaseInsensitive = true
+	cfg.PreserveOrder = true
+	previous := "z=1&B=2&a=3"
+	expected := "z=1&b=new&a=3"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreserveOrder_AddReplaceMultipleKeepsPositionOfSameName(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "b"
+	cfg.NewValue = "new"
+	cfg.CaseInsensitive = true
+	cfg.PreserveOrder = true
+	previous := "z=1&B=2&a=3&b=4"
+	expected := "z=1&a=3&b=new"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreserveOrder_Disabled(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "b"
+	previous := "z=1&b=2&a=3"
+	expected := "a=3&z=1"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreserveEncoding_Modify(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "b"
+	cfg.NewValue = "new value"
+	cfg.PreserveEncoding = true
+	previous := "z=a%20b&b=2&a=c+d&e=%7e"
+	expected := "z=a%20b&b=new+value&a=c+d&e=%7e"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreserveEncoding_Delete(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "b"
+	cfg.PreserveEncoding = true
+	previous := "z=a%20b&b=2&a=c+d&b=3&flag"
+	expected := "z=a%20b&a=c+d&flag"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreserveEncoding_AddAppends(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "a"
+	cfg.NewValue = "x y"
+	cfg.PreserveEncoding = true
+	previous := "z=a%20b&a=c+d"
+	expected := "z=a%20b&a=c+d&a=x+y"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestPreserveEncoding_Disabled(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "b"
+	previous := "z=a%20b&b=2&a=c+d"
+	expected := "a=c+d&z=a+b"
+
+	assertRawQueryModification(t, cfg, previous, expected)
+}
+
+func TestMatchRawName_DecodedByDefault(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "user-id"
+
+	assertQueryModification(t, cfg, "user%2Did=1&a=2", "a=2")
+}
+
+func TestMatchRawName_EncodedNameNotMatchedByDefault(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "user%2Did"
+
+	assertQueryModification(t, cfg, "user%2Did=1&a=2", "a=2&user-id=1")
+}
+
+func TestMatchRawName_Enabled(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "user%2Did"
+	cfg.MatchRawName = true
+
+	assertQueryModification(t, cfg, "user%2Did=1&a=2", "a=2")
+	assertQueryModification(t, cfg, "user-id=1&a=2", "a=2&user-id=1")
+}
+
+func TestMatchRawName_Regex(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamNameRegex = "%2D"
+	cfg.NewValue = "x"
+	cfg.MatchRawName = true
+
+	assertQueryModification(t, cfg, "user%2Did=1&user-name=2", "user-id=x&user-name=2")
+}
+
+func TestMatchRawValue_DecodedByDefault(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamValueRegex = `^a\+b$`
+
+	// a+b decodes to "a b" and is not matched
+	assertQueryModification(t, cfg, "x=a%2Bb&y=a+b&z=1", "y=a+b&z=1")
+}
+
+func TestMatchRawValue_EncodedValueNotMatchedByDefault(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamValueRegex = "%2B"
+
+	assertQueryModification(t, cfg, "x=a%2Bb&z=1", "x=a%2Bb&z=1")
+}
+
+func TestMatchRawValue_Enabled(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamValueRegex = "%2B"
+	cfg.MatchRawValue = true
+
+	// a+b decodes to "a b", only the encoded plus sign is matched
+	assertQueryModification(t, cfg, "x=a%2Bb&y=a+b&z=1", "y=a+b&z=1")
+}
+
+func TestMatchRawValue_Modify(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamValueRegex = `^a\+b$`
+	cfg.NewValue = "plus"
+	cfg.MatchRawValue = true
+
+	assertQueryModification(t, cfg, "x=a%2Bb&y=a+b", "x=a%2Bb&y=plus")
+}
+
+func TestMatchRawValue_RequiresValueRegex(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "x"
+	cfg.MatchRawValue = true
+
+	_, err := traefik_plugin_parameters.New(context.Background(), nil, cfg, "query-modification-plugin")
+	if err == nil || !strings.Contains(err.Error(), "matchRawValue") {
+		t.Errorf("expected an error about matchRawValue, got %v", err)
+	}
+}
+
+func TestMatchEmptyValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameRegex = ".*"
+	cfg.MatchEmptyValue = true
+
+	assertQueryModification(t, cfg, "debug&trace=&foo=1", "debug=&foo=1")
+}
+
+func TestMatchPresentNoValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameRegex = ".*"
+	cfg.MatchPresentNoValue = true
+
+	assertQueryModification(t, cfg, "debug&trace=&foo=1", "foo=1&trace=")
+}
+
+func TestMatchEmptyValue_BothForms(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamNameRegex = ".*"
+	cfg.NewValue = "1"
+	cfg.MatchEmptyValue = true
+	cfg.MatchPresentNoValue = true
+
+	assertQueryModification(t, cfg, "debug&trace=&foo=2", "debug=1&foo=2&trace=1")
+}
+
+func TestMatchEmptyValue_WithoutNameMatcher(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.MatchPresentNoValue = true
+
+	assertQueryModification(t, cfg, "debug&trace=&foo=1", "foo=1&trace=")
+}
+
+func TestMatchEmptyValue_Negated(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameRegex = ".*"
+	cfg.MatchPresentNoValue = true
+	cfg.NegateValueMatch = true
+
+	// every value except the ones without "=" is deleted
+	assertQueryModification(t, cfg, "debug&trace=&foo=1", "debug=")
+}
+
+func TestMatchEmptyValue_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    traefik_plugin_parameters.RuleConfig
+		errMsg string
+	}{
+		{
+			name:   "header target",
+			cfg:    traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "debug", Target: "header", MatchEmptyValue: true},
+			errMsg: "matchEmptyValue and matchPresentNoValue can only be used with the query target",
+		},
+		{
+			name:   "add type",
+			cfg:    traefik_plugin_parameters.RuleConfig{Type: "add", ParamName: "debug", NewValue: "1", MatchPresentNoValue: true},
+			errMsg: "matchEmptyValue and matchPresentNoValue have no effect for type add",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = tt.cfg
+
+			err := traefik_plugin_parameters.ValidateConfig(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestSpaceEncoding_PlusByDefault(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "q"
+	cfg.NewValue = "hello world"
+
+	assertRawQueryModification(t, cfg, "a=1%2B1", "a=1%2B1&q=hello+world")
+
+	cfg.SpaceEncoding = "plus"
+	assertRawQueryModification(t, cfg, "a=1%2B1", "a=1%2B1&q=hello+world")
+}
+
+func TestSpaceEncoding_Percent(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "q"
+	cfg.NewValue = "hello world"
+	cfg.SpaceEncoding = "percent"
+
+	// the encoded plus sign is kept, only spaces are encoded differently
+	assertRawQueryModification(t, cfg, "a=1%2B1&b=x+y", "a=1%2B1&b=x%20y&q=hello%20world")
+}
+
+func TestSpaceEncoding_PercentPreservingEncoding(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "q"
+	cfg.NewValue = "a b+c"
+	cfg.PreserveEncoding = true
+	cfg.SpaceEncoding = "percent"
+
+	assertRawQueryModification(t, cfg, "q=x&z=1%2B1", "q=a%20b%2Bc&z=1%2B1")
+}
+
+func TestSpaceEncoding_PercentKeepsPreservedParams(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "q"
+	cfg.NewValue = "a b"
+	cfg.PreserveEncoding = true
+	cfg.SpaceEncoding = "percent"
+
+	// only the modified param is re-encoded, the unchanged one keeps its raw form byte-for-byte
+	assertRawQueryModification(t, cfg, "q=x&z=x+y", "q=a%20b&z=x+y")
+}
+
+func TestSpaceEncoding_Invalid(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.SpaceEncoding = "underscore"
+
+	err := traefik_plugin_parameters.ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "invalid spaceEncoding") {
+		t.Errorf("expected an error about spaceEncoding, got %v", err)
+	}
+}
+
+func TestLiteralChars_Comma(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "fields"
+	cfg.NewValue = "id,name,email"
+	cfg.LiteralChars = ","
+
+	assertRawQueryModification(t, cfg, "ids=1,2%2C3&q=a/b", "fields=id,name,email&ids=1,2,3&q=a%2Fb")
+}
+
+func TestLiteralChars_EncodedByDefault(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "fields"
+	cfg.NewValue = "id,name"
+
+	assertRawQueryModification(t, cfg, "a=1", "a=1&fields=id%2Cname")
+}
+
+func TestLiteralChars_PreserveEncoding(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "tags"
+	cfg.NewValue = "$1,new"
+	cfg.LiteralChars = ",/"
+	cfg.PreserveEncoding = true
+
+	// the lowercase encoded form passed through from the raw query is decoded as well
+	assertRawQueryModification(t, cfg, "path=%2fa%2Fb&tags=a&x=1%2c2", "path=/a/b&tags=a,new&x=1,2")
+}
+
+func TestLiteralChars_Reserved(t *testing.T) {
+	for _, chars := range []string{"&", ",=", "%", "+", "#", ";", " ", "ä"} {
+		cfg := traefik_plugin_parameters.CreateConfig()
+		cfg.Type = "delete"
+		cfg.ParamName = "a"
+		cfg.LiteralChars = chars
+
+		err := traefik_plugin_parameters.ValidateConfig(cfg)
+		if err == nil || !strings.Contains(err.Error(), "literalChars") {
+			t.Errorf("expected an error about literalChars for %q, got %v", chars, err)
+		}
+	}
+}
+
+func assertRawQueryModification(t *testing.T, cfg *traefik_plugin_parameters.Config, previous, expected string) {
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req.URL.RawQuery = previous
+	handler.ServeHTTP(recorder, req)
+
+	if req.URL.RawQuery != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.RawQuery)
+	}
+}
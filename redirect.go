@@ -0,0 +1,132 @@
+package traefik_plugin_parameters
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// compileRedirectRules creates the rules applied to redirect locations, which are the redirectRules if set
+// or the given request rules otherwise. It returns nil without rewriteRedirects.
+func compileRedirectRules(config *Config, rules []*rule, logger *logger) ([]*rule, error) {
+	if !config.RewriteRedirects {
+		if len(config.RedirectRules) > 0 {
+			return nil, errors.New("redirectRules can only be used together with rewriteRedirects")
+		}
+		return nil, nil
+	}
+	if len(config.RedirectRules) == 0 {
+		return rules, nil
+	}
+
+	return compileQueryRules(config, config.RedirectRules, "redirectRules", logger)
+}
+
+// compileQueryRules creates the rules of the given configs of the given field, which may only modify the query.
+func compileQueryRules(config *Config, ruleConfigs []RuleConfig, field string, logger *logger) ([]*rule, error) {
+	rules := make([]*rule, 0, len(ruleConfigs))
+	for i := range ruleConfigs {
+		ruleConfig := &ruleConfigs[i]
+		if ruleConfig.Target != "" && ruleConfig.Target != queryTarget || len(ruleConfig.Targets) > 0 ||
+			ruleConfig.Type == copyToHeaderType || ruleConfig.PathTemplate != "" {
+			return nil, fmt.Errorf("%s[%d]: only the query can be modified", field, i)
+		}
+
+		rs, err := newRules(ruleConfig, logger, config.maxRegexLength(), config.StrictMatchers)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+		}
+		rules = append(rules, nameRules(rs, ruleConfig.Name, fmt.Sprintf("%s[%d]", field, i), logger)...)
+	}
+	return rules, nil
+}
+
+// rewriteLocation applies the redirect rules to the query of the given redirect location of the given request.
+// Conditions of the rules are evaluated against the forwarded request. Locations which cannot be parsed are returned unchanged.
+func (q *QueryModification) rewriteLocation(req *http.Request, location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		q.logger.Warnf("msg=\"could not parse redirect location, leaving it unchanged\" error=%q", err)
+		return location
+	}
+
+	rawQuery := q.splitQuery(u.RawQuery)
+	qry, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		q.logger.Warnf("msg=\"could not parse query of redirect location, leaving it unchanged\" error=%q", err)
+		return location
+	}
+
+	state := &requestState{req: req, header: req.Header.Clone(), counter: &q.counter}
+	if !q.applyQueryRules(req.Context(), q.redirectRules, qry, state) {
+		return location
+	}
+
+	// the raw names and values of the location are unknown to the rules, so they are derived from the decoded ones
+	u.RawQuery = q.joinQuery(q.encodeQuery(rawQuery, qry, nil))
+	if q.config.DryRun {
+		q.logger.Warnf("msg=\"dry run\" target=location before=%q after=%q", location, u.String())
+		return location
+	}
+	q.logger.Debugf("msg=\"rewrote redirect location\" before=%q after=%q", location, u.String())
+	return u.String()
+}
+
+// redirectWriter rewrites the Location header of redirect responses before the header is written
+type redirectWriter struct {
+	http.ResponseWriter
+	rewrite     func(location string) string
+	wroteHeader bool
+}
+
+func (w *redirectWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader && statusCode >= 300 && statusCode < 400 {
+		if location := w.Header().Get("Location"); location != "" {
+			w.Header().Set("Location", w.rewrite(location))
+		}
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *redirectWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data to the client if supported by the wrapped writer
+func (w *redirectWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack takes over the connection if supported by the wrapped writer, e.g. for WebSocket upgrades
+func (w *redirectWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not implement http.Hijacker", w.ResponseWriter)
+	}
+	return hijacker.Hijack()
+}
+
+// CloseNotify forwards to the wrapped writer, the returned channel never fires if it is not supported
+func (w *redirectWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// Push initiates an HTTP/2 server push if supported by the wrapped writer
+func (w *redirectWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
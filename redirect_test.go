@@ -0,0 +1,185 @@
+package traefik_plugin_parameters_test
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestRewriteRedirects_SameRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamNameRegex = "^utm_"
+	cfg.RewriteRedirects = true
+
+	recorder, req := serveRedirect(t, cfg, "http://localhost/old?utm_source=x&a=1", http.StatusFound, "https://example.com/new?utm_medium=y&b=2")
+
+	if req.URL.RawQuery != "a=1" {
+		t.Errorf("Expected the request query a=1, got %s", req.URL.RawQuery)
+	}
+	if location := recorder.Header().Get("Location"); location != "https://example.com/new?b=2" {
+		t.Errorf("Expected location https://example.com/new?b=2, got %s", location)
+	}
+	if recorder.Code != http.StatusFound {
+		t.Errorf("Expected status %d, got %d", http.StatusFound, recorder.Code)
+	}
+}
+
+func TestRewriteRedirects_SeparateRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.RewriteRedirects = true
+	cfg.RedirectRules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "rename", ParamName: "session", NewName: "sid"},
+	}
+
+	recorder, req := serveRedirect(t, cfg, "http://localhost/old?a=1&session=2", http.StatusMovedPermanently, "/new?a=1&session=3")
+
+	if req.URL.RawQuery != "session=2" {
+		t.Errorf("Expected the request query session=2, got %s", req.URL.RawQuery)
+	}
+	if location := recorder.Header().Get("Location"); location != "/new?a=1&sid=3" {
+		t.Errorf("Expected location /new?a=1&sid=3, got %s", location)
+	}
+}
+
+func TestRewriteRedirects_NoRedirect(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.RewriteRedirects = true
+
+	recorder, _ := serveRedirect(t, cfg, "http://localhost/", http.StatusCreated, "/items?a=1")
+
+	if location := recorder.Header().Get("Location"); location != "/items?a=1" {
+		t.Errorf("Expected the location of a non-redirect response unchanged, got %s", location)
+	}
+}
+
+func TestRewriteRedirects_Disabled(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+
+	recorder, _ := serveRedirect(t, cfg, "http://localhost/", http.StatusFound, "/items?a=1")
+
+	if location := recorder.Header().Get("Location"); location != "/items?a=1" {
+		t.Errorf("Expected the location unchanged, got %s", location)
+	}
+}
+
+func TestRewriteRedirects_DryRun(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.RewriteRedirects = true
+	cfg.DryRun = true
+	cfg.LogLevel = "none"
+
+	recorder, _ := serveRedirect(t, cfg, "http://localhost/", http.StatusFound, "/items?a=1")
+
+	if location := recorder.Header().Get("Location"); location != "/items?a=1" {
+		t.Errorf("Expected the location unchanged, got %s", location)
+	}
+}
+
+func TestRewriteRedirects_Errors(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		config        traefik_plugin_parameters.Config
+		expectedError string
+	}{
+		{
+			desc: "redirectRules without rewriteRedirects",
+			config: traefik_plugin_parameters.Config{
+				RedirectRules: []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "a"}},
+			},
+			expectedError: "redirectRules can only be used together with rewriteRedirects",
+		},
+		{
+			desc: "header target",
+			config: traefik_plugin_parameters.Config{
+				RewriteRedirects: true,
+				RedirectRules:    []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "a", Target: "header"}},
+			},
+			expectedError: "redirectRules[0]: only the query can be modified",
+		},
+		{
+			desc: "invalid rule",
+			config: traefik_plugin_parameters.Config{
+				RewriteRedirects: true,
+				RedirectRules:    []traefik_plugin_parameters.RuleConfig{{Type: "delete"}},
+			},
+			expectedError: "redirectRules[0]: either paramNameRegex",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := test.config
+
+			err := traefik_plugin_parameters.ValidateConfig(&cfg)
+			if err == nil || !strings.HasPrefix(err.Error(), test.expectedError) {
+				t.Errorf("expected error starting with %q, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRewriteRedirects_Hijack(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.RewriteRedirects = true
+
+	recorder := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		hijacker, ok := rw.(http.Hijacker)
+		if !ok {
+			t.Fatal("Expected the writer to implement http.Hijacker")
+		}
+		_, _, _ = hijacker.Hijack()
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/ws?a=1", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	newHandler(t, cfg, next).ServeHTTP(recorder, req)
+
+	if !recorder.hijacked {
+		t.Error("Expected the connection to be hijacked")
+	}
+}
+
+func serveRedirect(t *testing.T, cfg *traefik_plugin_parameters.Config, target string, status int, location string) (*httptest.ResponseRecorder, *http.Request) {
+	t.Helper()
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Location", location)
+		rw.WriteHeader(status)
+		_, _ = rw.Write([]byte("moved"))
+	})
+	return serve(newHandler(t, cfg, next), http.MethodGet, target)
+}
+
+// hijackRecorder is a recorder supporting http.Hijacker, which only records whether Hijack and WriteHeader were called
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked    bool
+	wroteHeader bool
+}
+
+func (r *hijackRecorder) WriteHeader(statusCode int) {
+	r.wroteHeader = true
+	r.ResponseRecorder.WriteHeader(statusCode)
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
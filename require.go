@@ -0,0 +1,35 @@
+package traefik_plugin_parameters
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// defaultMissingParamStatus is the status of requests lacking requireParam if missingParamStatus is not set
+const defaultMissingParamStatus = http.StatusBadRequest
+
+// missingParamStatus returns the status of requests lacking requireParam
+func (q *QueryModification) missingParamStatus() int {
+	if q.config.MissingParamStatus == 0 {
+		return defaultMissingParamStatus
+	}
+	return q.config.MissingParamStatus
+}
+
+// checkRequiredParam reports whether the query of the given request contains requireParam.
+// Malformed queries are treated as lacking the param. In dry run mode, missing params are only logged.
+func (q *QueryModification) checkRequiredParam(req *http.Request) bool {
+	qry, err := url.ParseQuery(q.splitQuery(req.URL.RawQuery))
+	if err == nil {
+		if _, ok := qry[q.config.RequireParam]; ok {
+			return true
+		}
+	}
+
+	if q.config.DryRun {
+		q.logger.Warnf("msg=\"dry run\" target=requireParam param=%q result=%q", q.config.RequireParam, "rejected")
+		return true
+	}
+	q.logger.Debugf("msg=\"rejected request with missing param\" param=%q path=%q", q.config.RequireParam, req.URL.Path)
+	return false
+}
@@ -0,0 +1,93 @@
+package traefik_plugin_parameters_test
+
+import (
+	"net/http"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestRequireParam_Present(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RequireParam = "api_key"
+
+	req, recorder, called := serveSigned(t, cfg, "api_key=&a=1")
+
+	if !called {
+		t.Fatal("Expected next to be called")
+	}
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if expected := "api_key=&a=1"; req.URL.RawQuery != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.RawQuery)
+	}
+}
+
+func TestRequireParam_Absent(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RequireParam = "api_key"
+
+	_, recorder, called := serveSigned(t, cfg, "a=1")
+
+	if called {
+		t.Error("Expected next not to be called")
+	}
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestRequireParam_AbsentWithStatus(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RequireParam = "api_key"
+	cfg.MissingParamStatus = http.StatusUnauthorized
+
+	_, recorder, called := serveSigned(t, cfg, "a=1")
+
+	if called {
+		t.Error("Expected next not to be called")
+	}
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, recorder.Code)
+	}
+}
+
+func TestRequireParam_DryRun(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RequireParam = "api_key"
+	cfg.DryRun = true
+
+	_, _, called := serveSigned(t, cfg, "a=1")
+
+	if !called {
+		t.Error("Expected next to be called")
+	}
+}
+
+func TestRequireParam_WithRule(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RequireParam = "api_key"
+	cfg.Type = "delete"
+	cfg.ParamName = "api_key"
+
+	req, _, called := serveSigned(t, cfg, "api_key=secret&a=1")
+
+	if !called {
+		t.Fatal("Expected next to be called")
+	}
+	if expected := "a=1"; req.URL.RawQuery != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.RawQuery)
+	}
+}
+
+func TestRequireParam_ErrorInvalidStatus(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RequireParam = "api_key"
+	cfg.MissingParamStatus = http.StatusOK
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
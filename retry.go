@@ -0,0 +1,183 @@
+package traefik_plugin_parameters
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// compileRetryRules validates retryOnStatus and creates the rules modifying the query of retried requests.
+// It returns nil without retryOnStatus.
+func compileRetryRules(config *Config, logger *logger) ([]*rule, error) {
+	if len(config.RetryOnStatus) == 0 {
+		if len(config.RetryRules) > 0 {
+			return nil, errors.New("retryRules can only be used together with retryOnStatus")
+		}
+		return nil, nil
+	}
+	if len(config.RetryRules) == 0 {
+		return nil, errors.New("retryRules must be set for retryOnStatus")
+	}
+	for _, status := range config.RetryOnStatus {
+		if status < 100 || status > 599 {
+			return nil, errors.New("retryOnStatus must only contain status codes between 100 and 599")
+		}
+	}
+
+	return compileQueryRules(config, config.RetryRules, "retryRules", logger)
+}
+
+// maxRetryBufferBytes limits the size of a response buffered to be able to discard it for a retry
+const maxRetryBufferBytes = 1 << 20
+
+// serveWithRetry passes the given GET request to the next handler.
+// Responses with a status listed in retryOnStatus are buffered up to maxRetryBufferBytes, all others are streamed.
+// If the retry rules change the query of a buffered response, it is discarded and the request is passed once more
+// with the changed query. Otherwise, the buffered response is written.
+func (q *QueryModification) serveWithRetry(rw http.ResponseWriter, req *http.Request) {
+	retry := &retryWriter{rw: rw, header: http.Header{}, retriesOn: q.retriesOn}
+	q.next.ServeHTTP(retry, req)
+	if retry.hijacked {
+		return
+	}
+	if retry.status == 0 {
+		retry.WriteHeader(http.StatusOK)
+	}
+	if !retry.buffering {
+		return
+	}
+
+	qry, err := url.ParseQuery(q.splitQuery(req.URL.RawQuery))
+	if err != nil || !q.applyQueryRules(req.Context(), q.retryRules, qry, &requestState{req: req, header: req.Header.Clone(), counter: &q.counter}) {
+		retry.release()
+		return
+	}
+
+	retryQuery := q.joinQuery(q.encodeQuery(q.splitQuery(req.URL.RawQuery), qry, nil))
+	if q.config.DryRun {
+		q.logger.Warnf("msg=\"dry run\" target=retry status=%d before=%q after=%q", retry.status, req.URL.RawQuery, retryQuery)
+		retry.release()
+		return
+	}
+
+	q.logger.Debugf("msg=\"retrying request\" status=%d before=%q after=%q", retry.status, req.URL.RawQuery, retryQuery)
+	req.URL.RawQuery = retryQuery
+	req.RequestURI = req.URL.RequestURI()
+	originalQuery, _ := OriginalQuery(req.Context())
+	q.next.ServeHTTP(rw, withQueries(req, originalQuery))
+}
+
+// isUpgrade reports whether the given request asks for a protocol upgrade, e.g. to WebSocket.
+// Upgraded connections are hijacked by the backend, so their responses can neither be buffered nor retried.
+func isUpgrade(req *http.Request) bool {
+	for _, value := range req.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retriesOn reports whether the given status is listed in retryOnStatus
+func (q *QueryModification) retriesOn(status int) bool {
+	for _, retryStatus := range q.config.RetryOnStatus {
+		if retryStatus == status {
+			return true
+		}
+	}
+	return false
+}
+
+// retryWriter decides on the status of a response whether to stream it to the underlying writer
+// or to keep it in memory, so it can be discarded for a retry.
+// A buffered response exceeding maxRetryBufferBytes is released and streamed from then on.
+type retryWriter struct {
+	rw        http.ResponseWriter
+	retriesOn func(status int) bool
+	header    http.Header
+	status    int
+	buffering bool
+	hijacked  bool
+	body      bytes.Buffer
+}
+
+func (w *retryWriter) Header() http.Header {
+	if w.status != 0 && !w.buffering {
+		return w.rw.Header()
+	}
+	return w.header
+}
+
+func (w *retryWriter) WriteHeader(statusCode int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = statusCode
+	if w.retriesOn(statusCode) {
+		w.buffering = true
+		return
+	}
+	w.writeHeader()
+}
+
+func (w *retryWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering && w.body.Len()+len(b) > maxRetryBufferBytes {
+		w.release()
+	}
+	if !w.buffering {
+		return w.rw.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+// Flush forwards to the underlying writer, buffered responses are not flushed
+func (w *retryWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return
+	}
+	if flusher, ok := w.rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack takes over the connection if supported by the underlying writer, buffered responses cannot be hijacked
+func (w *retryWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.buffering {
+		return nil, nil, errors.New("cannot hijack a buffered response")
+	}
+	hijacker, ok := w.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not implement http.Hijacker", w.rw)
+	}
+	w.hijacked = true
+	return hijacker.Hijack()
+}
+
+// writeHeader writes the header and the status to the underlying writer
+func (w *retryWriter) writeHeader() {
+	for key, values := range w.header {
+		w.rw.Header()[key] = values
+	}
+	w.rw.WriteHeader(w.status)
+}
+
+// release writes the buffered response to the underlying writer and stops buffering
+func (w *retryWriter) release() {
+	w.buffering = false
+	w.writeHeader()
+	_, _ = w.rw.Write(w.body.Bytes())
+	w.body.Reset()
+}
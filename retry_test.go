@@ -0,0 +1,230 @@
+package traefik_plugin_parameters_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestRetryOnStatus_RetriesWithModifiedQuery(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RetryOnStatus = []int{http.StatusNotFound}
+	cfg.RetryRules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "variant"}}
+
+	recorder, queries := serveRetry(t, cfg, http.MethodGet, "http://localhost/items?variant=beta&a=1")
+
+	if !reflect.DeepEqual(queries, []string{"variant=beta&a=1", "a=1"}) {
+		t.Errorf("Expected a retry without variant, got %q", queries)
+	}
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "found" || recorder.Header().Get("X-Attempt") != "2" {
+		t.Errorf("Expected the response of the retry, got %d %s %v", recorder.Code, recorder.Body.String(), recorder.Header())
+	}
+}
+
+func TestRetryOnStatus_NoRetryForOtherStatus(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RetryOnStatus = []int{http.StatusGone}
+	cfg.RetryRules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "variant"}}
+
+	recorder, queries := serveRetry(t, cfg, http.MethodGet, "http://localhost/items?variant=beta")
+
+	if !reflect.DeepEqual(queries, []string{"variant=beta"}) {
+		t.Errorf("Expected no retry, got %q", queries)
+	}
+	if recorder.Code != http.StatusNotFound || recorder.Body.String() != "not found" || recorder.Header().Get("X-Attempt") != "1" {
+		t.Errorf("Expected the buffered response, got %d %s %v", recorder.Code, recorder.Body.String(), recorder.Header())
+	}
+}
+
+func TestRetryOnStatus_NoRetryWithoutChange(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RetryOnStatus = []int{http.StatusNotFound}
+	cfg.RetryRules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "variant"}}
+
+	recorder, queries := serveRetry(t, cfg, http.MethodGet, "http://localhost/items?a=1&fail=1")
+
+	if !reflect.DeepEqual(queries, []string{"a=1&fail=1"}) {
+		t.Errorf("Expected no retry, got %q", queries)
+	}
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected the buffered response, got %d", recorder.Code)
+	}
+}
+
+func TestRetryOnStatus_OnlyOnce(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RetryOnStatus = []int{http.StatusNotFound}
+	cfg.RetryRules = []traefik_plugin_parameters.RuleConfig{{Type: "add", ParamName: "fail", NewValue: "1"}}
+
+	recorder, queries := serveRetry(t, cfg, http.MethodGet, "http://localhost/items?variant=beta")
+
+	if !reflect.DeepEqual(queries, []string{"variant=beta", "fail=1&variant=beta"}) {
+		t.Errorf("Expected a single retry, got %q", queries)
+	}
+	if recorder.Code != http.StatusNotFound || recorder.Header().Get("X-Attempt") != "2" {
+		t.Errorf("Expected the response of the retry, got %d %v", recorder.Code, recorder.Header())
+	}
+}
+
+func TestRetryOnStatus_GetOnly(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RetryOnStatus = []int{http.StatusNotFound}
+	cfg.RetryRules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "variant", ApplyToMethods: []string{"POST"}}}
+
+	_, queries := serveRetry(t, cfg, http.MethodPost, "http://localhost/items?variant=beta")
+
+	if !reflect.DeepEqual(queries, []string{"variant=beta"}) {
+		t.Errorf("Expected no retry, got %q", queries)
+	}
+}
+
+func TestRetryOnStatus_Errors(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		config        traefik_plugin_parameters.Config
+		expectedError string
+	}{
+		{
+			desc:          "retryOnStatus without retryRules",
+			config:        traefik_plugin_parameters.Config{RetryOnStatus: []int{404}, RuleConfig: traefik_plugin_parameters.RuleConfig{Type: "delete", ParamName: "a"}},
+			expectedError: "retryRules must be set for retryOnStatus",
+		},
+		{
+			desc:          "retryRules without retryOnStatus",
+			config:        traefik_plugin_parameters.Config{RetryRules: []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "a"}}},
+			expectedError: "retryRules can only be used together with retryOnStatus",
+		},
+		{
+			desc:          "invalid status",
+			config:        traefik_plugin_parameters.Config{RetryOnStatus: []int{4040}, RetryRules: []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "a"}}},
+			expectedError: "retryOnStatus must only contain status codes",
+		},
+		{
+			desc:          "header target",
+			config:        traefik_plugin_parameters.Config{RetryOnStatus: []int{404}, RetryRules: []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "a", Target: "header"}}},
+			expectedError: "retryRules[0]: only the query can be modified",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := test.config
+
+			err := traefik_plugin_parameters.ValidateConfig(&cfg)
+			if err == nil || !strings.HasPrefix(err.Error(), test.expectedError) {
+				t.Errorf("expected error starting with %q, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRetryOnStatus_StreamsOtherStatus(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RetryOnStatus = []int{http.StatusNotFound}
+	cfg.RetryRules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "variant"}}
+
+	recorder := httptest.NewRecorder()
+	var streamed string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte("data: 1\n\n"))
+		rw.(http.Flusher).Flush()
+		streamed = recorder.Body.String()
+	})
+	handler := newHandler(t, cfg, next)
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost/events?variant=beta", nil))
+
+	if streamed != "data: 1\n\n" || !recorder.Flushed {
+		t.Errorf("Expected the response to be streamed and flushed, got %q flushed=%t", streamed, recorder.Flushed)
+	}
+}
+
+func TestRetryOnStatus_NoRetryForLargeResponse(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RetryOnStatus = []int{http.StatusNotFound}
+	cfg.RetryRules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "variant"}}
+
+	body := strings.Repeat("x", 1<<20+1)
+	calls := 0
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		rw.WriteHeader(http.StatusNotFound)
+		_, _ = rw.Write([]byte(body[:10]))
+		_, _ = rw.Write([]byte(body[10:]))
+	})
+	handler := newHandler(t, cfg, next)
+	recorder, _ := serve(handler, http.MethodGet, "http://localhost/items?variant=beta")
+
+	if calls != 1 || recorder.Code != http.StatusNotFound || recorder.Body.String() != body {
+		t.Errorf("Expected the large response without a retry, got %d calls, status %d and %d bytes", calls, recorder.Code, recorder.Body.Len())
+	}
+}
+
+func TestRetryOnStatus_Upgrade(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RetryOnStatus = []int{http.StatusNotFound}
+	cfg.RetryRules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "variant"}}
+
+	recorder := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	calls := 0
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		hijacker, ok := rw.(http.Hijacker)
+		if !ok {
+			t.Fatal("Expected the writer to implement http.Hijacker")
+		}
+		_, _, _ = hijacker.Hijack()
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/ws?variant=beta", nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	newHandler(t, cfg, next).ServeHTTP(recorder, req)
+
+	if calls != 1 || !recorder.hijacked {
+		t.Errorf("Expected the connection to be hijacked without a retry, got %d calls hijacked=%t", calls, recorder.hijacked)
+	}
+}
+
+func TestRetryOnStatus_Hijack(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RetryOnStatus = []int{http.StatusNotFound}
+	cfg.RetryRules = []traefik_plugin_parameters.RuleConfig{{Type: "delete", ParamName: "variant"}}
+
+	recorder := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		hijacker, ok := rw.(http.Hijacker)
+		if !ok {
+			t.Fatal("Expected the writer to implement http.Hijacker")
+		}
+		_, _, _ = hijacker.Hijack()
+	})
+	newHandler(t, cfg, next).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost/items?variant=beta", nil))
+
+	if !recorder.hijacked || recorder.wroteHeader {
+		t.Errorf("Expected the connection to be hijacked without writing a response, got hijacked=%t wroteHeader=%t", recorder.hijacked, recorder.wroteHeader)
+	}
+}
+
+// serveRetry serves a request with a backend which fails with 404 if the query contains variant or fail,
+// it returns the response and the queries received by the backend
+func serveRetry(t *testing.T, cfg *traefik_plugin_parameters.Config, method, target string) (*httptest.ResponseRecorder, []string) {
+	t.Helper()
+
+	var queries []string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		queries = append(queries, req.URL.RawQuery)
+		rw.Header().Set("X-Attempt", strconv.Itoa(len(queries)))
+		if strings.Contains(req.URL.RawQuery, "variant") || strings.Contains(req.URL.RawQuery, "fail") {
+			rw.WriteHeader(http.StatusNotFound)
+			_, _ = rw.Write([]byte("not found"))
+			return
+		}
+		_, _ = rw.Write([]byte("found"))
+	})
+	recorder, _ := serve(newHandler(t, cfg, next), method, target)
+	return recorder, queries
+}
@@ -0,0 +1,1363 @@
+package traefik_plugin_parameters
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type modificationType string
+
+const (
+	addType          modificationType = "add"
+	modifyType       modificationType = "modify"
+	deleteType       modificationType = "delete"
+	addReplaceType   modificationType = "add-or-replace"
+	copyToHeaderType modificationType = "copy-to-header"
+	addIfAbsentType  modificationType = "add-if-absent"
+	renameType       modificationType = "rename"
+	clearType        modificationType = "clear"
+	splitType        modificationType = "split"
+	modifyOrAddType  modificationType = "modify-or-add"
+)
+
+// defaultSplitSeparator separates the values split by the split type if splitSeparator is not set
+const defaultSplitSeparator = ","
+
+type targetType string
+
+const (
+	queryTarget  targetType = "query"
+	headerTarget targetType = "header"
+	formTarget   targetType = "form"
+	matrixTarget targetType = "matrix"
+	jsonTarget   targetType = "json"
+)
+
+// RuleConfig is the configuration of a single modification rule
+type RuleConfig struct {
+	Type                  modificationType  `json:"type"`
+	ParamName             string            `json:"paramName"`
+	ParamNameRegex        string            `json:"paramNameRegex"`
+	ParamValueRegex       string            `json:"paramValueRegex"`
+	NewValue              string            `json:"newValue"`
+	NewValueRegex         string            `json:"newValueRegex"`
+	ApplyToMethods        []string          `json:"applyToMethods"`
+	Target                targetType        `json:"target"`
+	CaseInsensitive       bool              `json:"caseInsensitive"`
+	ValueOnly             bool              `json:"valueOnly"`
+	PathRegex             string            `json:"pathRegex"`
+	HeaderName            string            `json:"headerName"`
+	RemoveParam           bool              `json:"removeParam"`
+	JoinValues            bool              `json:"joinValues"`
+	Transform             transformType     `json:"transform"`
+	NewName               string            `json:"newName"`
+	ReplaceExisting       bool              `json:"replaceExisting"`
+	HashLength            int               `json:"hashLength"`
+	HashSalt              string            `json:"hashSalt"`
+	MatchFirstOnly        bool              `json:"matchFirstOnly"`
+	ProtectedParams       []string          `json:"protectedParams"`
+	ConditionParam        string            `json:"conditionParam"`
+	ConditionValueRegex   string            `json:"conditionValueRegex"`
+	ParamNameGlob         string            `json:"paramNameGlob"`
+	ParamValueGlob        string            `json:"paramValueGlob"`
+	ValueFromHeader       string            `json:"valueFromHeader"`
+	SchemeRegex           string            `json:"schemeRegex"`
+	RequirePresentParam   string            `json:"requirePresentParam"`
+	MatchRawName          bool              `json:"matchRawName"`
+	MatchRawValue         bool              `json:"matchRawValue"`
+	MatchEmptyValue       bool              `json:"matchEmptyValue"`
+	MatchPresentNoValue   bool              `json:"matchPresentNoValue"`
+	MinValue              int               `json:"minValue"`
+	MaxValue              int               `json:"maxValue"`
+	NewValues             []string          `json:"newValues"`
+	ValueFromEnv          string            `json:"valueFromEnv"`
+	Enabled               *bool             `json:"enabled"`
+	NewValueTemplate      string            `json:"newValueTemplate"`
+	ParamValueIn          []string          `json:"paramValueIn"`
+	PathTemplate          string            `json:"pathTemplate"`
+	Targets               []string          `json:"targets"`
+	ContentTypeRegex      string            `json:"contentTypeRegex"`
+	PathSource            pathSourceType    `json:"pathSource"`
+	ParamValueGreaterThan *int              `json:"paramValueGreaterThan"`
+	ParamValueLessThan    *int              `json:"paramValueLessThan"`
+	ValueMap              map[string]string `json:"valueMap"`
+	DeleteUnmapped        bool              `json:"deleteUnmapped"`
+	ParamNamePrefix       string            `json:"paramNamePrefix"`
+	ParamNameSuffix       string            `json:"paramNameSuffix"`
+	NegateNameMatch       bool              `json:"negateNameMatch"`
+	NegateValueMatch      bool              `json:"negateValueMatch"`
+	Transforms            []string          `json:"transforms"`
+	TrueValues            []string          `json:"trueValues"`
+	FalseValues           []string          `json:"falseValues"`
+	ActiveFrom            string            `json:"activeFrom"`
+	ActiveUntil           string            `json:"activeUntil"`
+	SplitSeparator        string            `json:"splitSeparator"`
+	Name                  string            `json:"name"`
+	ClientCIDRs           []string          `json:"clientCIDRs"`
+	CIDRMatchMode         cidrMatchMode     `json:"cidrMatchMode"`
+	TrustForwardedFor     bool              `json:"trustForwardedFor"`
+	TrustedProxies        []string          `json:"trustedProxies"`
+	ValueIndex            *int              `json:"valueIndex"`
+	ValuePrefix           string            `json:"valuePrefix"`
+	ValueSuffix           string            `json:"valueSuffix"`
+	AcceptRegex           string            `json:"acceptRegex"`
+	Preset                presetType        `json:"preset"`
+	ExtraParams           []string          `json:"extraParams"`
+}
+
+// rule is a validated modification rule with its regexes compiled
+type rule struct {
+	config                      *RuleConfig
+	logger                      *logger
+	paramNameRegexCompiled      *regexp.Regexp
+	paramValueRegexCompiled     *regexp.Regexp
+	pathRegexCompiled           *regexp.Regexp
+	conditionValueRegexCompiled *regexp.Regexp
+	schemeRegexCompiled         *regexp.Regexp
+	contentTypeRegexCompiled    *regexp.Regexp
+	acceptRegexCompiled         *regexp.Regexp
+	valueTemplate               *template.Template
+	// transforms are Transform or Transforms, applied in order
+	transforms []transformType
+	// activeFrom and activeUntil are the parsed ActiveFrom and ActiveUntil in UTC, zero if not set
+	activeFrom  time.Time
+	activeUntil time.Time
+	// name identifies the rule in logs, audit log entries and metrics
+	name string
+	// clientNetworks are the parsed ClientCIDRs
+	clientNetworks []*net.IPNet
+	// trustedProxies are the parsed TrustedProxies
+	trustedProxies []*net.IPNet
+}
+
+// requestState holds the data of the current request rules depend on besides the params they modify
+type requestState struct {
+	// req is the source of the data available in newValueTemplate
+	req *http.Request
+	// header is the source of valueFromHeader
+	header http.Header
+	// rawNames maps decoded query param names to their forms in the raw query, it is only set for matchRawName
+	rawNames map[string][]string
+	// rawValues maps decoded query param values to their forms in the raw query, it is only set for matchRawValue
+	rawValues map[string][]string
+	// emptyForms maps query param names to the forms of their empty values in the raw query,
+	// it is only set for matchEmptyValue and matchPresentNoValue
+	emptyForms map[string]emptyForm
+	// counter is the counter of the plugin instance .Counter in newValueTemplate is drawn from
+	counter *uint64
+	// requestCounter is the value of .Counter for the current request, zero until it is drawn on first use
+	requestCounter uint64
+}
+
+// newRules creates the rules for the given configuration, which are one rule per entry of Targets or a single rule otherwise
+func newRules(config *RuleConfig, logger *logger, maxRegexLength int, strictMatchers bool) ([]*rule, error) {
+	if len(config.Targets) == 0 {
+		r, err := newRule(config, logger, maxRegexLength, strictMatchers)
+		if err != nil {
+			return nil, err
+		}
+		return []*rule{r}, nil
+	}
+
+	if config.Target != "" {
+		return nil, errors.New("target and targets cannot be used together")
+	}
+
+	rules := make([]*rule, 0, len(config.Targets))
+	for i, name := range config.Targets {
+		target := targetType(name)
+		if target != queryTarget && target != headerTarget {
+			return nil, newConfigError(ErrInvalidTarget, "invalid targets, expected query / header")
+		}
+
+		// each target gets its own rule working on a copy of the config
+		targetConfig := *config
+		targetConfig.Target = target
+		targetConfig.Targets = nil
+		r, err := newRule(&targetConfig, logger, maxRegexLength, strictMatchers)
+		if err != nil {
+			return nil, fmt.Errorf("targets[%d]: %w", i, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// newRule validates the given configuration and compiles its regexes, which must not be longer than maxRegexLength.
+// With strictMatchers, combining multiple param matchers is an error instead of a warning.
+func newRule(config *RuleConfig, logger *logger, maxRegexLength int, strictMatchers bool) (*rule, error) {
+	if config.Preset != "" || len(config.ExtraParams) > 0 {
+		resolved, err := resolvePreset(config)
+		if err != nil {
+			return nil, err
+		}
+		config = resolved
+	}
+
+	if config.ParamNameGlob != "" && config.ParamNameRegex != "" {
+		return nil, errors.New("paramNameGlob and paramNameRegex cannot be used together")
+	}
+	if config.ParamValueGlob != "" && config.ParamValueRegex != "" {
+		return nil, errors.New("paramValueGlob and paramValueRegex cannot be used together")
+	}
+	if len(config.ParamValueIn) > 0 && containsNonEmpty(config.ParamValueRegex, config.ParamValueGlob) {
+		return nil, errors.New("paramValueIn cannot be used together with paramValueRegex or paramValueGlob")
+	}
+	maxValueRegexLength := maxRegexLength
+	if containsNonEmpty(config.ParamNameGlob, config.ParamValueGlob) || len(config.ParamValueIn) > 0 {
+		// globs and value lists are translated into the equivalent regexes on a copy, leaving the given config untouched
+		translated := *config
+		if config.ParamNameGlob != "" {
+			translated.ParamNameRegex = globToRegex(config.ParamNameGlob)
+		}
+		if config.ParamValueGlob != "" {
+			translated.ParamValueRegex = globToRegex(config.ParamValueGlob)
+		}
+		if len(config.ParamValueIn) > 0 {
+			translated.ParamValueRegex = valueListToRegex(config.ParamValueIn, config.CaseInsensitive)
+			// the length of the list is not limited
+			maxValueRegexLength = len(translated.ParamValueRegex)
+		}
+		config = &translated
+	}
+
+	if config.Type == "" {
+		return nil, newConfigError(ErrInvalidType, "type must be set, expected add / add-or-replace / add-if-absent / modify / rename / delete / copy-to-header / clear / split / modify-or-add")
+	}
+
+	if !config.Type.isValid() {
+		return nil, newConfigError(ErrInvalidType, "invalid modification type, expected add / add-or-replace / add-if-absent / modify / rename / delete / copy-to-header / clear / split / modify-or-add")
+	}
+
+	if !config.Target.isValid() {
+		return nil, newConfigError(ErrInvalidTarget, "invalid target, expected query / header / form / matrix / json")
+	}
+
+	if config.Type == copyToHeaderType && config.Target != "" && config.Target != queryTarget {
+		return nil, newConfigError(ErrInvalidTarget, "copy-to-header can only be used with the query target")
+	}
+
+	if config.Type == clearType && config.Target != "" && config.Target != queryTarget {
+		return nil, newConfigError(ErrInvalidTarget, "clear can only be used with the query target")
+	}
+
+	if config.Target == jsonTarget {
+		if config.Type != modifyType && config.Type != deleteType {
+			return nil, errors.New("the json target can only be used with type modify or delete")
+		}
+		if config.ParamName == "" || containsNonEmpty(config.ParamNameRegex, config.ParamNamePrefix, config.ParamNameSuffix) || config.NegateNameMatch {
+			return nil, errors.New("the json target requires the path of the field in paramName and supports no other name matchers")
+		}
+	}
+
+	switch config.Type {
+	case addType, addReplaceType, addIfAbsentType, copyToHeaderType, modifyOrAddType:
+		// the name of the param to add is required, further matchers are optional
+		if config.ParamName == "" {
+			return nil, newConfigError(ErrNoMatcher, fmt.Sprintf("paramName must be set for type %q", config.Type))
+		}
+	case clearType:
+		// all params are removed, so no matchers are required
+	default:
+		if !containsNonEmpty(config.ParamNameRegex, config.ParamName, config.ParamValueRegex, config.ParamNamePrefix, config.ParamNameSuffix) && !config.hasValueComparison() && !config.hasEmptyValueMatcher() {
+			return nil, newConfigError(ErrNoMatcher, fmt.Sprintf("either paramNameRegex or paramName or paramValueRegex or a name prefix / suffix or a value comparison or an empty value matcher must be set for type %q", config.Type))
+		}
+	}
+
+	// modify-or-add only matches the values of paramName, so its matchers do not target different params
+	if config.Type != modifyOrAddType && (config.ParamNameRegex != "" && containsNonEmpty(config.ParamName, config.ParamValueRegex) ||
+		config.ParamName != "" && containsNonEmpty(config.ParamNameRegex, config.ParamValueRegex) ||
+		config.ParamValueRegex != "" && containsNonEmpty(config.ParamName, config.ParamNameRegex)) {
+		if strictMatchers {
+			return nil, errors.New("multiple param matchers must not be used at once with strictMatchers")
+		}
+		logger.Warnf("msg=%q", "It is discouraged to use multiple param matchers at once. Please proceed with caution")
+	}
+
+	if err := validateFieldCombinations(config); err != nil {
+		return nil, err
+	}
+
+	if config.NewValue != "" && len(config.NewValues) > 0 {
+		logger.Warnf("msg=%q", "newValue is ignored as newValues is set")
+	}
+
+	if config.ValueFromEnv != "" {
+		// the value is resolved once, so the environment is not read per request
+		value, ok := os.LookupEnv(config.ValueFromEnv)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %q referenced by valueFromEnv is not set", config.ValueFromEnv)
+		}
+		resolved := *config
+		resolved.NewValue = value
+		config = &resolved
+	}
+
+	if config.Type == renameType && config.NewName == "" {
+		return nil, errors.New("newName must be set for type rename")
+	}
+
+	if config.NewValueRegex != "" && config.ParamValueRegex == "" {
+		return nil, errors.New("newValueRegex can only be used together with paramValueRegex")
+	}
+
+	if config.Transform != "" && len(config.Transforms) > 0 {
+		return nil, errors.New("transform and transforms cannot be used together")
+	}
+
+	var transforms []transformType
+	if config.Transform != "" {
+		if err := config.Transform.validate(config); err != nil {
+			return nil, err
+		}
+		transforms = append(transforms, config.Transform)
+	}
+	for i, name := range config.Transforms {
+		t := transformType(name)
+		if t == "" {
+			return nil, fmt.Errorf("transforms[%d]: transform must not be empty", i)
+		}
+		if err := t.validate(config); err != nil {
+			return nil, fmt.Errorf("transforms[%d]: %w", i, err)
+		}
+		transforms = append(transforms, t)
+	}
+
+	if len(config.TrueValues) > 0 || len(config.FalseValues) > 0 {
+		normalizesBool := false
+		for _, t := range transforms {
+			normalizesBool = normalizesBool || t == normalizeBoolTransform
+		}
+		if !normalizesBool {
+			return nil, errors.New("trueValues and falseValues can only be used with transform normalizeBool")
+		}
+	}
+
+	if len(transforms) > 0 && config.Type != modifyType {
+		return nil, errors.New("transform and transforms can only be used with the modify type")
+	}
+
+	if config.MatchFirstOnly && config.Type != modifyType && config.Type != deleteType {
+		return nil, errors.New("matchFirstOnly can only be used with the modify or delete type")
+	}
+
+	if containsNonEmpty(config.ValuePrefix, config.ValueSuffix) && config.Type != modifyType {
+		return nil, errors.New("valuePrefix and valueSuffix can only be used with the modify type")
+	}
+
+	if config.ValueIndex != nil {
+		if config.Type != modifyType {
+			return nil, errors.New("valueIndex can only be used with the modify type")
+		}
+		if *config.ValueIndex < -1 {
+			return nil, errors.New("valueIndex must be -1 (all values) or a 0-based index")
+		}
+		if config.MatchFirstOnly {
+			return nil, errors.New("valueIndex and matchFirstOnly cannot be used together")
+		}
+	}
+
+	if config.ValueOnly {
+		if config.Type != modifyType || config.ParamValueRegex == "" {
+			return nil, errors.New("valueOnly can only be used with the modify type and paramValueRegex")
+		}
+		if containsNonEmpty(config.ParamName, config.ParamNameRegex, config.ParamNameGlob, config.ParamNamePrefix, config.ParamNameSuffix) || config.NegateNameMatch {
+			return nil, errors.New("valueOnly cannot be used together with paramName, paramNameRegex, paramNameGlob, paramNamePrefix, paramNameSuffix or negateNameMatch")
+		}
+		if len(config.ValueMap) > 0 || config.NewValueTemplate != "" {
+			return nil, errors.New("valueOnly cannot be used together with valueMap or newValueTemplate")
+		}
+	}
+
+	clientNetworks, err := parseClientCIDRs(config)
+	if err != nil {
+		return nil, err
+	}
+	trustedProxies, err := parseTrustedProxies(config)
+	if err != nil {
+		return nil, err
+	}
+
+	activeFrom, err := parseTimestamp("activeFrom", config.ActiveFrom)
+	if err != nil {
+		return nil, err
+	}
+	activeUntil, err := parseTimestamp("activeUntil", config.ActiveUntil)
+	if err != nil {
+		return nil, err
+	}
+	if !activeFrom.IsZero() && !activeUntil.IsZero() && !activeFrom.Before(activeUntil) {
+		return nil, errors.New("activeFrom must be before activeUntil")
+	}
+
+	var paramNameRegexCompiled *regexp.Regexp = nil
+	if config.ParamNameRegex != "" {
+		paramNameRegex := config.ParamNameRegex
+		if config.Target == headerTarget {
+			// header names are case-insensitive
+			paramNameRegex = "(?i)" + paramNameRegex
+		}
+
+		maxNameRegexLength := maxRegexLength
+		if config.Preset != "" {
+			// like value lists, the length of the preset and its extra params is not limited
+			maxNameRegexLength = len(paramNameRegex)
+		}
+
+		var err error
+		paramNameRegexCompiled, err = compileRegex("paramNameRegex", paramNameRegex, maxNameRegexLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var paramValueRegexCompiled *regexp.Regexp = nil
+	if config.ParamValueRegex != "" {
+		var err error
+		paramValueRegexCompiled, err = compileRegex("paramValueRegex", config.ParamValueRegex, maxValueRegexLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var pathRegexCompiled *regexp.Regexp = nil
+	if config.PathRegex != "" {
+		var err error
+		pathRegexCompiled, err = compileRegex("pathRegex", config.PathRegex, maxRegexLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var schemeRegexCompiled *regexp.Regexp = nil
+	if config.SchemeRegex != "" {
+		var err error
+		schemeRegexCompiled, err = compileRegex("schemeRegex", config.SchemeRegex, maxRegexLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var contentTypeRegexCompiled *regexp.Regexp = nil
+	if config.ContentTypeRegex != "" {
+		var err error
+		contentTypeRegexCompiled, err = compileRegex("contentTypeRegex", config.ContentTypeRegex, maxRegexLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var acceptRegexCompiled *regexp.Regexp = nil
+	if config.AcceptRegex != "" {
+		var err error
+		acceptRegexCompiled, err = compileRegex("acceptRegex", config.AcceptRegex, maxRegexLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if (config.ConditionParam == "") != (config.ConditionValueRegex == "") {
+		return nil, errors.New("conditionParam and conditionValueRegex must be used together")
+	}
+
+	var conditionValueRegexCompiled *regexp.Regexp = nil
+	if config.ConditionValueRegex != "" {
+		var err error
+		conditionValueRegexCompiled, err = compileRegex("conditionValueRegex", config.ConditionValueRegex, maxRegexLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	valueTemplate, err := parseValueTemplate(config.NewValueTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("newValueTemplate: %w", err)
+	}
+
+	return &rule{
+		config:                      config,
+		logger:                      logger,
+		paramNameRegexCompiled:      paramNameRegexCompiled,
+		paramValueRegexCompiled:     paramValueRegexCompiled,
+		pathRegexCompiled:           pathRegexCompiled,
+		conditionValueRegexCompiled: conditionValueRegexCompiled,
+		schemeRegexCompiled:         schemeRegexCompiled,
+		contentTypeRegexCompiled:    contentTypeRegexCompiled,
+		acceptRegexCompiled:         acceptRegexCompiled,
+		valueTemplate:               valueTemplate,
+		transforms:                  transforms,
+		activeFrom:                  activeFrom,
+		activeUntil:                 activeUntil,
+		clientNetworks:              clientNetworks,
+		trustedProxies:              trustedProxies,
+	}, nil
+}
+
+// valueListToRegex translates the given list of values into a regex matching exactly these values
+func valueListToRegex(values []string, caseInsensitive bool) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, regexp.QuoteMeta(value))
+	}
+
+	regex := "^(?:" + strings.Join(quoted, "|") + ")$"
+	if caseInsensitive {
+		regex = "(?i)" + regex
+	}
+	return regex
+}
+
+// compileRegex compiles the regex of the given config field.
+// Errors are prefixed with the field name, so the failing field can be identified.
+func compileRegex(field, pattern string, maxLength int) (*regexp.Regexp, error) {
+	if len(pattern) > maxLength {
+		return nil, newConfigError(ErrRegexTooLong, fmt.Sprintf("%s: regex exceeds the maximum length of %d", field, maxLength))
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, &RegexError{Field: field, Err: err}
+	}
+	return compiled, nil
+}
+
+// parseTimestamp parses the given RFC 3339 timestamp of the given field in UTC, the zero time is returned for an empty timestamp
+func parseTimestamp(field, timestamp string) (time.Time, error) {
+	if timestamp == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp: %w", field, err)
+	}
+	return t.UTC(), nil
+}
+
+// validateFieldCombinations rejects fields which have no effect for the configured type,
+// as these are most likely typos or misunderstandings of the configuration.
+func validateFieldCombinations(config *RuleConfig) error {
+	switch config.Type {
+	case deleteType:
+		if containsNonEmpty(config.NewValue, config.NewValueRegex) {
+			return errors.New("newValue and newValueRegex have no effect for type delete")
+		}
+	case addType, addIfAbsentType:
+		if containsNonEmpty(config.ParamNameRegex, config.ParamValueRegex, config.ParamNamePrefix, config.ParamNameSuffix) {
+			return fmt.Errorf("paramNameRegex, paramValueRegex, paramNamePrefix and paramNameSuffix have no effect for type %s", config.Type)
+		}
+	case modifyOrAddType:
+		if containsNonEmpty(config.ParamNameRegex, config.ParamNameGlob, config.ParamNamePrefix, config.ParamNameSuffix) || config.NegateNameMatch {
+			return errors.New("type modify-or-add only targets paramName, name matchers have no effect")
+		}
+	case clearType:
+		if containsNonEmpty(config.ParamName, config.ParamNameRegex, config.ParamValueRegex, config.ParamNameGlob, config.ParamValueGlob, config.ParamNamePrefix, config.ParamNameSuffix, config.NewValue, config.NewValueRegex) {
+			return errors.New("matchers and new values have no effect for type clear")
+		}
+	case renameType:
+		if containsNonEmpty(config.NewValue, config.NewValueRegex) {
+			return errors.New("newValue and newValueRegex have no effect for type rename, use newName instead")
+		}
+	case splitType:
+		if containsNonEmpty(config.NewValue, config.NewValueRegex) {
+			return errors.New("newValue and newValueRegex have no effect for type split")
+		}
+	case copyToHeaderType:
+		if containsNonEmpty(config.NewValue, config.NewValueRegex) {
+			return errors.New("newValue and newValueRegex have no effect for type copy-to-header")
+		}
+	}
+
+	if config.hasValueComparison() {
+		switch config.Type {
+		case addType, addIfAbsentType, clearType:
+			return fmt.Errorf("paramValueGreaterThan and paramValueLessThan have no effect for type %s", config.Type)
+		}
+		if config.ParamValueGreaterThan != nil && config.ParamValueLessThan != nil && *config.ParamValueGreaterThan >= *config.ParamValueLessThan {
+			return errors.New("paramValueGreaterThan must be less than paramValueLessThan")
+		}
+	}
+
+	if config.NegateNameMatch || config.NegateValueMatch {
+		switch config.Type {
+		case addType, addIfAbsentType, clearType:
+			return fmt.Errorf("negateNameMatch and negateValueMatch have no effect for type %s", config.Type)
+		}
+	}
+
+	if config.NegateNameMatch && !containsNonEmpty(config.ParamName, config.ParamNameRegex, config.ParamNamePrefix, config.ParamNameSuffix) {
+		return errors.New("negateNameMatch requires a name matcher")
+	}
+
+	if config.NegateValueMatch && config.ParamValueRegex == "" && !config.hasValueComparison() && !config.hasEmptyValueMatcher() {
+		return errors.New("negateValueMatch requires a value matcher")
+	}
+
+	if len(config.ValueMap) > 0 {
+		if config.Type != modifyType {
+			return errors.New("valueMap can only be used with type modify")
+		}
+		if containsNonEmpty(config.NewValue, config.NewValueRegex, config.NewValueTemplate) {
+			return errors.New("valueMap cannot be used together with newValue, newValueRegex or newValueTemplate")
+		}
+	} else if config.DeleteUnmapped {
+		return errors.New("deleteUnmapped can only be used together with valueMap")
+	}
+
+	if config.Type != renameType && config.ReplaceExisting {
+		return errors.New("replaceExisting can only be used with type rename")
+	}
+
+	if config.Type != renameType && config.Type != splitType && config.NewName != "" {
+		return errors.New("newName can only be used with type rename or split")
+	}
+
+	if config.Type != splitType && config.SplitSeparator != "" {
+		return errors.New("splitSeparator can only be used with type split")
+	}
+
+	if len(config.NewValues) > 0 && config.Type != addType && config.Type != addReplaceType && config.Type != addIfAbsentType {
+		return errors.New("newValues can only be used with type add, add-or-replace or add-if-absent")
+	}
+
+	if config.ValueFromEnv != "" {
+		if config.Type != addType && config.Type != addReplaceType && config.Type != addIfAbsentType {
+			return errors.New("valueFromEnv can only be used with type add, add-or-replace or add-if-absent")
+		}
+		if config.NewValue != "" || len(config.NewValues) > 0 {
+			return errors.New("valueFromEnv cannot be used together with newValue or newValues")
+		}
+	}
+
+	if config.PathTemplate != "" {
+		if config.Type != deleteType && config.Type != modifyType || config.Target != "" && config.Target != queryTarget {
+			return errors.New("pathTemplate can only be used with type delete or modify and the query target")
+		}
+		if !strings.HasPrefix(config.PathTemplate, "/") {
+			return errors.New("pathTemplate must start with /")
+		}
+	}
+
+	if config.NewValueTemplate != "" && config.Type != addType && config.Type != addReplaceType && config.Type != addIfAbsentType && config.Type != modifyType && config.Type != modifyOrAddType {
+		return errors.New("newValueTemplate can only be used with type add, add-or-replace, add-if-absent, modify or modify-or-add")
+	}
+
+	if config.ValueFromHeader != "" && config.Type != addType && config.Type != addReplaceType {
+		return errors.New("valueFromHeader can only be used with type add or add-or-replace")
+	}
+
+	if !config.PathSource.isValid() {
+		return errors.New("invalid pathSource, expected path / forwarded-uri")
+	}
+
+	if config.PathSource != "" && config.PathRegex == "" {
+		return errors.New("pathSource can only be used together with pathRegex")
+	}
+
+	if config.MatchRawName && config.Target != "" && config.Target != queryTarget {
+		return newConfigError(ErrInvalidTarget, "matchRawName can only be used with the query target")
+	}
+
+	if config.MatchRawValue && config.Target != "" && config.Target != queryTarget {
+		return newConfigError(ErrInvalidTarget, "matchRawValue can only be used with the query target")
+	}
+
+	if config.MatchRawValue && config.ParamValueRegex == "" {
+		return errors.New("matchRawValue requires paramValueRegex")
+	}
+
+	if config.hasEmptyValueMatcher() {
+		if config.Target != "" && config.Target != queryTarget {
+			return newConfigError(ErrInvalidTarget, "matchEmptyValue and matchPresentNoValue can only be used with the query target")
+		}
+		switch config.Type {
+		case addType, addIfAbsentType, clearType:
+			return fmt.Errorf("matchEmptyValue and matchPresentNoValue have no effect for type %s", config.Type)
+		}
+	}
+
+	if config.Type != copyToHeaderType && (config.HeaderName != "" || config.RemoveParam || config.JoinValues) {
+		return errors.New("headerName, removeParam and joinValues can only be used with type copy-to-header")
+	}
+
+	return nil
+}
+
+// isSet reports whether any of the fields identifying a rule is set
+func (c *RuleConfig) isSet() bool {
+	return c.Type != "" || c.Preset != "" || len(c.ParamValueIn) > 0 || c.hasValueComparison() || c.hasEmptyValueMatcher() ||
+		containsNonEmpty(c.ParamName, c.ParamNameRegex, c.ParamValueRegex, c.ParamNameGlob, c.ParamValueGlob, c.ParamNamePrefix, c.ParamNameSuffix)
+}
+
+// hasValueComparison reports whether the values are compared numerically
+func (c *RuleConfig) hasValueComparison() bool {
+	return c.ParamValueGreaterThan != nil || c.ParamValueLessThan != nil
+}
+
+// hasEmptyValueMatcher reports whether empty values are matched by the form they have in the raw query
+func (c *RuleConfig) hasEmptyValueMatcher() bool {
+	return c.MatchEmptyValue || c.MatchPresentNoValue
+}
+
+// modifyParams applies the modification of this rule to the given params,
+// which are either the query params or the headers of a request.
+// It returns the names of the params whose values were changed.
+func (r *rule) modifyParams(params map[string][]string, state *requestState) []string {
+	var changed []string
+	switch r.config.Type {
+	case addType:
+		values, ok := r.addValues(state)
+		if !ok {
+			break
+		}
+		key := r.paramKey()
+		params[key] = append(params[key], values...)
+		changed = append(changed, key)
+	case addIfAbsentType:
+		if r.hasParam(params, state) {
+			break
+		}
+		values, ok := r.addValues(state)
+		if !ok {
+			break
+		}
+		key := r.paramKey()
+		params[key] = append(params[key], values...)
+		changed = append(changed, key)
+	case deleteType:
+		var removed []string
+		changed, removed = r.deleteParams(params, state)
+		if len(removed) > 0 {
+			r.logger.Debugf("msg=\"deleted values\" target=%s removed=%q", r.config.Target.orDefault(), removed)
+		}
+	case clearType:
+		for key := range params {
+			if !r.isProtected(key) {
+				delete(params, key)
+				changed = append(changed, key)
+			}
+		}
+	case addReplaceType:
+		values, ok := r.addValues(state)
+		if !ok {
+			break
+		}
+		key := r.paramKey()
+		paramsToDelete := determineAffectedParams(params, r, state)
+		for _, paramToDelete := range paramsToDelete {
+			delete(params, paramToDelete)
+			if paramToDelete != key {
+				changed = append(changed, paramToDelete)
+			}
+		}
+		params[key] = append(params[key], values...)
+		changed = append(changed, key)
+	case renameType:
+		newKey := r.config.NewName
+		if r.config.Target == headerTarget {
+			newKey = http.CanonicalHeaderKey(newKey)
+		}
+
+		var values []string
+		for _, paramToRename := range determineAffectedParams(params, r, state) {
+			if paramToRename == newKey {
+				continue
+			}
+			values = append(values, params[paramToRename]...)
+			delete(params, paramToRename)
+			changed = append(changed, paramToRename)
+		}
+
+		if len(changed) > 0 {
+			if r.config.ReplaceExisting {
+				params[newKey] = values
+			} else {
+				params[newKey] = append(params[newKey], values...)
+			}
+		}
+	case splitType:
+		changed = r.splitParams(params, state)
+	case modifyOrAddType:
+		changed = r.modifyOrAddParam(params, state)
+	case modifyType:
+		paramsToModify := determineAffectedParams(params, r, state)
+		for _, paramToModify := range paramsToModify {
+			oldValues := params[paramToModify]
+			newValues := r.modifyValues(paramToModify, oldValues, state)
+			// affected params are determined before, so replacing the values has no side effects on other params
+			if len(newValues) == 0 {
+				// all values were unmapped and deleted
+				delete(params, paramToModify)
+			} else {
+				params[paramToModify] = newValues
+			}
+			if !equalValues(oldValues, newValues) {
+				changed = append(changed, paramToModify)
+			}
+		}
+	}
+	return changed
+}
+
+// splitParams splits the values of the affected params on SplitSeparator and stores the non-empty parts
+// under NewName, or under the name of the split param if NewName is not set.
+// It returns the names of the params which were split.
+func (r *rule) splitParams(params map[string][]string, state *requestState) []string {
+	separator := r.config.SplitSeparator
+	if separator == "" {
+		separator = defaultSplitSeparator
+	}
+	newKey := r.config.NewName
+	if newKey != "" && r.config.Target == headerTarget {
+		newKey = http.CanonicalHeaderKey(newKey)
+	}
+
+	var changed []string
+	for _, paramToSplit := range determineAffectedParams(params, r, state) {
+		oldValues := params[paramToSplit]
+		var newValues []string
+		for _, value := range oldValues {
+			for _, part := range strings.Split(value, separator) {
+				if part != "" {
+					newValues = append(newValues, part)
+				}
+			}
+		}
+
+		targetKey := newKey
+		if targetKey == "" {
+			targetKey = paramToSplit
+		}
+		delete(params, paramToSplit)
+		if len(newValues) > 0 {
+			params[targetKey] = append(params[targetKey], newValues...)
+		}
+		if targetKey != paramToSplit || !equalValues(oldValues, newValues) {
+			changed = append(changed, paramToSplit)
+		}
+	}
+	return changed
+}
+
+// modifyOrAddParam modifies the values of paramName like modify if any of them is targeted by the value matchers,
+// otherwise the values are added like add. It returns the name of the param if it was changed.
+func (r *rule) modifyOrAddParam(params map[string][]string, state *requestState) []string {
+	key := r.paramKey()
+	oldValues := params[key]
+	if r.anyValueMatches(key, oldValues, state) {
+		newValues := r.modifyValues(key, oldValues, state)
+		if equalValues(oldValues, newValues) {
+			return nil
+		}
+		params[key] = newValues
+		return []string{key}
+	}
+
+	values, ok := r.addValues(state)
+	if !ok {
+		return nil
+	}
+	params[key] = append(oldValues, values...)
+	return []string{key}
+}
+
+// addValues returns the values to add, which are taken from the header valueFromHeader if set.
+// Otherwise, or if that header is absent, newValueTemplate, newValues or newValue are used, in this order of precedence.
+// If the header is absent and neither of them is set, the rule is skipped, indicated by false.
+func (r *rule) addValues(state *requestState) ([]string, bool) {
+	if r.config.ValueFromHeader != "" {
+		if values := state.header.Values(r.config.ValueFromHeader); len(values) > 0 {
+			return values[:1], true
+		}
+	}
+	if r.valueTemplate != nil {
+		value, err := r.renderValue(state, r.paramKey(), "")
+		if err != nil {
+			r.logger.Warnf("msg=\"could not execute newValueTemplate, skipping the rule\" error=%q", err)
+			return nil, false
+		}
+		return []string{value}, true
+	}
+	if len(r.config.NewValues) > 0 {
+		return r.config.NewValues, true
+	}
+	return []string{r.config.NewValue}, r.config.ValueFromHeader == "" || r.config.NewValue != ""
+}
+
+// modifyValues computes the new values of the param with the given key and values.
+func (r *rule) modifyValues(key string, oldValues []string, state *requestState) []string {
+	newValueTemplate, newValueRegexTemplate := r.config.NewValue, r.config.NewValueRegex
+	if r.paramNameRegexCompiled != nil {
+		// the capture groups of the name are substituted before the ones of the value
+		newValueTemplate = r.expandNameGroups(newValueTemplate, key, false)
+		newValueRegexTemplate = r.expandNameGroups(newValueRegexTemplate, key, true)
+	}
+
+	valueIndex := -1
+	if r.config.ValueIndex != nil {
+		valueIndex = *r.config.ValueIndex
+	}
+	if valueIndex >= len(oldValues) {
+		r.logger.Warnf("msg=\"valueIndex is out of range, leaving the param unchanged\" param=%q valueIndex=%d values=%d", key, valueIndex, len(oldValues))
+		return oldValues
+	}
+
+	newValues := make([]string, 0, len(oldValues))
+	targetedValues := 0
+	for i, oldValue := range oldValues {
+		var newValue string
+		if (valueIndex == -1 || i == valueIndex) && r.matchesValue(key, oldValue, state) && (!r.config.MatchFirstOnly || targetedValues == 0) {
+			targetedValues++
+			if r.config.ValueOnly {
+				// The whole matching value is replaced, regardless of the param it belongs to,
+				// the other values of the param are left unchanged
+				newValues = append(newValues, r.replaceValue(oldValue, newValueTemplate, newValueRegexTemplate))
+				continue
+			}
+			if len(r.config.ValueMap) > 0 {
+				// The value is looked up in valueMap, which cannot be combined with the other replacements,
+				// unmapped values are left unchanged or deleted
+				mapped, ok := r.config.ValueMap[oldValue]
+				if !ok && r.config.DeleteUnmapped {
+					continue
+				}
+				if !ok {
+					mapped = oldValue
+				}
+				newValue = mapped
+			} else if r.valueTemplate != nil {
+				// case 0: The template takes precedence over all other replacements,
+				// if it cannot be executed the value is left unchanged
+				rendered, err := r.renderValue(state, key, oldValue)
+				if err != nil {
+					r.logger.Warnf("msg=\"could not execute newValueTemplate, leaving value unchanged\" error=%q", err)
+					newValues = append(newValues, oldValue)
+					continue
+				}
+				newValue = rendered
+			} else if r.paramValueRegexCompiled != nil && r.config.NewValueRegex != "" {
+				// case 1: The regex for the query value matches and NewValueRegex is not empty
+				// then use these to determine the new value
+				newValue = r.paramValueRegexCompiled.ReplaceAllString(oldValue, newValueRegexTemplate)
+			} else if r.config.NewValue == "" && (len(r.transforms) > 0 || containsNonEmpty(r.config.ValuePrefix, r.config.ValueSuffix)) {
+				// case 2: There is no replacement but a transformation or affixes,
+				// then transform the old value
+				newValue = oldValue
+			} else {
+				// case 3: There is no regex for the query value or it didn't match
+				// (because the query key is in here for some other reason (i.e. the key matches)
+				// then use the non-regex as replacement (maybe replace "$1" with the old value)
+				newValue = strings.ReplaceAll(newValueTemplate, "$1", oldValue)
+			}
+			// the affixes are added after all other modifications
+			newValue = r.config.ValuePrefix + r.transform(newValue) + r.config.ValueSuffix
+		} else {
+			// case 4: There is a value regex which didn't match or only the first value or another index is targeted
+			// we do nothing then
+			newValue = oldValue
+		}
+		newValues = append(newValues, newValue)
+	}
+	return newValues
+}
+
+// replaceValue computes the new value of a value matched by paramValueRegex in the valueOnly mode.
+// Unlike the modify type without valueOnly, newValueRegex is expanded with the capture groups of the first match
+// into the whole new value, instead of only replacing the matched parts of the value.
+func (r *rule) replaceValue(value, newValueTemplate, newValueRegexTemplate string) string {
+	var newValue string
+	switch {
+	case r.config.NewValueRegex != "":
+		match := r.paramValueRegexCompiled.FindStringSubmatchIndex(value)
+		newValue = string(r.paramValueRegexCompiled.ExpandString(nil, newValueRegexTemplate, value, match))
+	case r.config.NewValue == "" && (len(r.transforms) > 0 || containsNonEmpty(r.config.ValuePrefix, r.config.ValueSuffix)):
+		newValue = value
+	default:
+		newValue = strings.ReplaceAll(newValueTemplate, "$1", value)
+	}
+	return r.config.ValuePrefix + r.transform(newValue) + r.config.ValueSuffix
+}
+
+// deleteParams deletes the params and values targeted by this rule from the given params.
+// It returns the names of the params whose values were deleted and the deleted pairs in the form key=value.
+func (r *rule) deleteParams(params map[string][]string, state *requestState) ([]string, []string) {
+	var changed, removed []string
+	for _, paramToDelete := range determineAffectedParams(params, r, state) {
+		oldValues := params[paramToDelete]
+		if !r.hasValueMatcher() && !r.config.MatchFirstOnly {
+			delete(params, paramToDelete)
+			changed = append(changed, paramToDelete)
+			removed = appendPairs(removed, paramToDelete, oldValues)
+			continue
+		}
+
+		// only delete the targeted values, the param is removed once no value is left
+		newValues, deletedValues := r.deleteValues(paramToDelete, oldValues, state)
+		if len(newValues) == 0 {
+			delete(params, paramToDelete)
+		} else {
+			params[paramToDelete] = newValues
+		}
+		if len(deletedValues) > 0 {
+			changed = append(changed, paramToDelete)
+			removed = appendPairs(removed, paramToDelete, deletedValues)
+		}
+	}
+	return changed, removed
+}
+
+// appendPairs appends the given values of the param with the given key in the form key=value
+func appendPairs(pairs []string, key string, values []string) []string {
+	for _, value := range values {
+		pairs = append(pairs, key+"="+value)
+	}
+	return pairs
+}
+
+// deleteValues splits the given values into the ones kept and the ones targeted by this rule,
+// which are the ones matching paramValueRegex (if set) or only the first of them with MatchFirstOnly.
+func (r *rule) deleteValues(key string, oldValues []string, state *requestState) ([]string, []string) {
+	newValues := make([]string, 0, len(oldValues))
+	var deletedValues []string
+	for _, oldValue := range oldValues {
+		if r.matchesValue(key, oldValue, state) && (!r.config.MatchFirstOnly || len(deletedValues) == 0) {
+			deletedValues = append(deletedValues, oldValue)
+			continue
+		}
+		newValues = append(newValues, oldValue)
+	}
+	return newValues, deletedValues
+}
+
+// hasValueMatcher reports whether the rule targets values by paramValueRegex, a numeric comparison or their empty form
+func (r *rule) hasValueMatcher() bool {
+	return r.paramValueRegexCompiled != nil || r.config.hasValueComparison() || r.config.hasEmptyValueMatcher()
+}
+
+// matchesValue reports whether the given value matches paramValueRegex and the numeric comparisons,
+// any value matches without them. Values which are no integers never match a comparison.
+// With NegateValueMatch the result of the matchers is inverted.
+func (r *rule) matchesValue(key, value string, state *requestState) bool {
+	if !r.hasValueMatcher() {
+		return true
+	}
+	return r.matchesValueMatchers(key, value, state) != r.config.NegateValueMatch
+}
+
+// matchesValueMatchers reports whether the given value of the param with the given key matches all configured value matchers
+func (r *rule) matchesValueMatchers(key, value string, state *requestState) bool {
+	if r.paramValueRegexCompiled != nil && !r.matchesValueRegex(value, state) {
+		return false
+	}
+	if r.config.hasEmptyValueMatcher() && !r.matchesEmptyValue(key, value, state) {
+		return false
+	}
+	if !r.config.hasValueComparison() {
+		return true
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	if r.config.ParamValueGreaterThan != nil && number <= int64(*r.config.ParamValueGreaterThan) {
+		return false
+	}
+	return r.config.ParamValueLessThan == nil || number < int64(*r.config.ParamValueLessThan)
+}
+
+// matchesValueRegex reports whether paramValueRegex matches the given value.
+// With MatchRawValue it is matched against the forms of the value in the raw query instead.
+func (r *rule) matchesValueRegex(value string, state *requestState) bool {
+	if !r.config.MatchRawValue {
+		return r.paramValueRegexCompiled.MatchString(value)
+	}
+
+	forms, ok := state.rawValues[value]
+	if !ok {
+		// the value was added by a previous rule
+		forms = []string{url.QueryEscape(value)}
+	}
+	for _, form := range forms {
+		if r.paramValueRegexCompiled.MatchString(form) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesEmptyValue reports whether the given value is empty in one of the forms selected by matchEmptyValue and matchPresentNoValue.
+// If the param occurs with both forms in the raw query, its empty values match either option.
+func (r *rule) matchesEmptyValue(key, value string, state *requestState) bool {
+	if value != "" {
+		return false
+	}
+
+	forms, ok := state.emptyForms[key]
+	if !ok {
+		// the value was set by a previous rule or the query was not parsed from a raw query, it is encoded with a "="
+		forms = emptyWithEquals
+	}
+	return r.config.MatchEmptyValue && forms&emptyWithEquals != 0 ||
+		r.config.MatchPresentNoValue && forms&emptyWithoutEquals != 0
+}
+
+// anyValueMatches reports whether any of the given values of the param with the given key matches the value matchers
+func (r *rule) anyValueMatches(key string, values []string, state *requestState) bool {
+	for _, value := range values {
+		if r.matchesValue(key, value, state) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameGroupPattern matches references to capture groups of paramNameRegex, e.g. ${name:1} or ${name:group}
+var nameGroupPattern = regexp.MustCompile(`\$\{name:(\w+)\}`)
+
+// expandNameGroups replaces the references to capture groups of paramNameRegex in the given template
+// with the groups captured from the given key. References to groups which did not match are replaced by an empty string.
+// If the template is used as regex replacement later on, "$" within the groups is escaped.
+func (r *rule) expandNameGroups(template, key string, escape bool) string {
+	if template == "" || !strings.Contains(template, "${name:") {
+		return template
+	}
+
+	groups := r.paramNameRegexCompiled.FindStringSubmatch(key)
+	return nameGroupPattern.ReplaceAllStringFunc(template, func(reference string) string {
+		group := nameGroupPattern.FindStringSubmatch(reference)[1]
+		value := ""
+		for i, groupName := range r.paramNameRegexCompiled.SubexpNames() {
+			if i < len(groups) && (groupName == group || strconv.Itoa(i) == group) {
+				value = groups[i]
+				break
+			}
+		}
+
+		if escape {
+			value = strings.ReplaceAll(value, "$", "$$")
+		}
+		return value
+	})
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// transform applies the configured transformations in order to the given value.
+// Values which cannot be transformed by any of them are left unchanged.
+func (r *rule) transform(value string) string {
+	transformed := value
+	for _, t := range r.transforms {
+		var err error
+		transformed, err = t.apply(transformed, r.config)
+		if err != nil {
+			r.logger.Warnf("msg=\"could not apply transform, leaving value unchanged\" transform=%s error=%q", t, err)
+			return value
+		}
+	}
+	return transformed
+}
+
+// copyToHeader sets the header HeaderName (or ParamName if not set) to the value of the affected query params.
+// Multiple values are joined if JoinValues is set, otherwise the first value is used.
+// It returns the names of the params which were copied, removing them is left to removeCopied.
+func (r *rule) copyToHeader(qry url.Values, state *requestState) []string {
+	var values []string
+	paramsToCopy := determineAffectedParams(qry, r, state)
+	for _, key := range paramsToCopy {
+		values = append(values, qry[key]...)
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	if r.config.JoinValues {
+		state.header.Set(r.headerName(), strings.Join(values, ", "))
+	} else {
+		state.header.Set(r.headerName(), values[0])
+	}
+	return paramsToCopy
+}
+
+// removeCopied removes the given copied params from the query if RemoveParam is set.
+// It returns the names of the removed params.
+func (r *rule) removeCopied(qry url.Values, copied []string) []string {
+	if !r.config.RemoveParam {
+		return nil
+	}
+	for _, key := range copied {
+		qry.Del(key)
+	}
+	return copied
+}
+
+// headerName returns the name of the header the params are copied to, which defaults to ParamName
+func (r *rule) headerName() string {
+	if r.config.HeaderName == "" {
+		return r.config.ParamName
+	}
+	return r.config.HeaderName
+}
+
+// paramKey returns the key under which new values for ParamName are stored in the target.
+func (r *rule) paramKey() string {
+	if r.config.Target == headerTarget {
+		return http.CanonicalHeaderKey(r.config.ParamName)
+	}
+	return r.config.ParamName
+}
+
+// determineAffectedParams returns the sorted names of the params targeted by the given rule
+func determineAffectedParams(params map[string][]string, r *rule, state *requestState) []string {
+	var result []string
+	for key, values := range params {
+		if r.isProtected(key) {
+			continue
+		}
+
+		if r.matchesName(key, state) ||
+			(r.hasValueMatcher() && r.anyValueMatches(key, values, state)) {
+			result = append(result, key)
+		}
+	}
+
+	// map iteration order is random, sorting keeps the order of changes and logs reproducible
+	sort.Strings(result)
+	return result
+}
+
+// hasParam reports whether the given params contain a param matching ParamName
+func (r *rule) hasParam(params map[string][]string, state *requestState) bool {
+	for key := range params {
+		for _, name := range r.namesToMatch(key, state) {
+			if r.matchesParamName(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesName reports whether the given key matches ParamName, ParamNameRegex or the name affixes.
+// With NegateNameMatch the result is inverted, so only keys matching none of them are matched.
+func (r *rule) matchesName(key string, state *requestState) bool {
+	for _, name := range r.namesToMatch(key, state) {
+		if r.matchesParamName(name) || r.matchesNameAffixes(name) ||
+			r.paramNameRegexCompiled != nil && r.paramNameRegexCompiled.MatchString(name) {
+			return !r.config.NegateNameMatch
+		}
+	}
+	return r.config.NegateNameMatch
+}
+
+// namesToMatch returns the names the name matchers are applied to for the given key.
+// With MatchRawName these are the forms of the key in the raw query, otherwise the decoded key itself.
+func (r *rule) namesToMatch(key string, state *requestState) []string {
+	if !r.config.MatchRawName {
+		return []string{key}
+	}
+	if names, ok := state.rawNames[key]; ok {
+		return names
+	}
+	// the param was added by a previous rule
+	return []string{url.QueryEscape(key)}
+}
+
+// matchesParamName reports whether the given key equals ParamName.
+func (r *rule) matchesParamName(key string) bool {
+	return r.config.ParamName != "" && r.equalNames(r.config.ParamName, key)
+}
+
+// matchesNameAffixes reports whether the given key starts with ParamNamePrefix and ends with ParamNameSuffix,
+// at least one of them has to be set.
+func (r *rule) matchesNameAffixes(key string) bool {
+	prefix, suffix := r.config.ParamNamePrefix, r.config.ParamNameSuffix
+	if prefix == "" && suffix == "" || len(key) < len(prefix)+len(suffix) {
+		return false
+	}
+	return r.equalNames(prefix, key[:len(prefix)]) && r.equalNames(suffix, key[len(key)-len(suffix):])
+}
+
+// isProtected reports whether the given key is listed in ProtectedParams.
+func (r *rule) isProtected(key string) bool {
+	for _, protected := range r.config.ProtectedParams {
+		if r.equalNames(protected, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalNames reports whether the given param names are equal.
+// Header names are case-insensitive, so the comparison always folds case for the header target.
+func (r *rule) equalNames(a, b string) bool {
+	if r.config.CaseInsensitive || r.config.Target == headerTarget {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+func anyMatch(values []string, regex *regexp.Regexp) bool {
+	for _, value := range values {
+		if regex.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (mt modificationType) isValid() bool {
+	switch mt {
+	case addType, modifyType, deleteType, addReplaceType, copyToHeaderType, addIfAbsentType, renameType, clearType, splitType, modifyOrAddType:
+		return true
+	}
+
+	return false
+}
+
+func (t targetType) isValid() bool {
+	switch t {
+	case queryTarget, headerTarget, formTarget, matrixTarget, jsonTarget, "":
+		return true
+	}
+
+	return false
+}
+
+// orDefault returns the target, which is the query if the target is not set
+func (t targetType) orDefault() targetType {
+	if t == "" {
+		return queryTarget
+	}
+	return t
+}
+
+func containsNonEmpty(ss ...string) bool {
+	for _, s := range ss {
+		if s != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package traefik_plugin_parameters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// readRulesFile reads the rules from the JSON file at the given path, which contains an array of rule configurations.
+// Unknown fields are rejected, so typos in the file do not silently disable options.
+func readRulesFile(path string) ([]RuleConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read rulesFile: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	var rules []RuleConfig
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("could not parse rulesFile %q: %w", path, err)
+	}
+	return rules, nil
+}
@@ -0,0 +1,77 @@
+package traefik_plugin_parameters_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestRulesFile_Loaded(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RulesFile = writeRulesFile(t, `[
+		{"type": "delete", "paramNameRegex": "^utm_"},
+		{"type": "add", "paramName": "source", "newValue": "proxy"}
+	]`)
+	previous := "utm_source=news&id=1"
+	expected := "id=1&source=proxy"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestRulesFile_AfterInlineRules(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "add", ParamName: "a", NewValue: "inline"},
+	}
+	cfg.RulesFile = writeRulesFile(t, `[{"type": "modify", "paramName": "a", "newValue": "$1-file"}]`)
+	previous := "b=1"
+	expected := "a=inline-file&b=1"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestRulesFile_Errors(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		content       string
+		expectedError string
+	}{
+		{desc: "invalid JSON", content: `[{"type": "delete",`, expectedError: "could not parse rulesFile"},
+		{desc: "unknown field", content: `[{"type": "delete", "paramNam": "a"}]`, expectedError: "unknown field"},
+		{desc: "invalid rule", content: `[{"type": "delete", "paramName": "a"}, {"type": "unknown", "paramName": "a"}]`, expectedError: "rulesFile[1]"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RulesFile = writeRulesFile(t, test.content)
+			_, err, _, _ := createReqAndRecorder(cfg)
+
+			if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("Expected error containing %s, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRulesFile_ErrorMissingFile(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.RulesFile = filepath.Join(t.TempDir(), "missing.json")
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil || !strings.Contains(err.Error(), "could not read rulesFile") {
+		t.Errorf("Expected error about the missing file, got %v", err)
+	}
+}
+
+// writeRulesFile writes the given content to a temporary file and returns its path.
+func writeRulesFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
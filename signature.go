@@ -0,0 +1,81 @@
+package traefik_plugin_parameters
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+)
+
+// defaultSignatureParam is the name of the param holding the signature if signatureParam is not set
+const defaultSignatureParam = "sig"
+
+// signatureParam returns the name of the param holding the signature
+func (q *QueryModification) signatureParam() string {
+	if q.config.SignatureParam == "" {
+		return defaultSignatureParam
+	}
+	return q.config.SignatureParam
+}
+
+// verifySignature reports whether the signature param of the given query is a valid hex encoded HMAC-SHA256
+// of the signed params. The signed params are encoded like url.Values.Encode, so sorted by name.
+// Without signedParams, all params except the signature itself are signed.
+func (q *QueryModification) verifySignature(qry url.Values) bool {
+	signatures := qry[q.signatureParam()]
+	if len(signatures) != 1 {
+		return false
+	}
+	signature, err := hex.DecodeString(signatures[0])
+	if err != nil {
+		return false
+	}
+
+	signed := url.Values{}
+	if len(q.config.SignedParams) == 0 {
+		for key, values := range qry {
+			if key != q.signatureParam() {
+				signed[key] = values
+			}
+		}
+	} else {
+		for _, key := range q.config.SignedParams {
+			if values, ok := qry[key]; ok {
+				signed[key] = values
+			}
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(q.config.SignatureSecret))
+	mac.Write([]byte(signed.Encode()))
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// checkSignature verifies the signature of the given request and strips the signature param afterwards.
+// It reports false if the signature is missing or invalid, in which case the request must be rejected.
+// In dry run mode, invalid signatures are only logged and the request is left untouched.
+func (q *QueryModification) checkSignature(req *http.Request) bool {
+	rawQuery := q.splitQuery(req.URL.RawQuery)
+	qry, err := url.ParseQuery(rawQuery)
+	if err != nil || !q.verifySignature(qry) {
+		if q.config.DryRun {
+			q.logger.Warnf("msg=\"dry run\" target=signature result=%q", "rejected")
+			return true
+		}
+		q.logger.Debugf("msg=\"rejected request with missing or invalid signature\" path=%q", req.URL.Path)
+		return false
+	}
+
+	if !q.config.DryRun {
+		stripped := make(url.Values, len(qry))
+		for key, values := range qry {
+			if key != q.signatureParam() {
+				stripped[key] = values
+			}
+		}
+		req.URL.RawQuery = q.joinQuery(encodePreserving(rawQuery, qry, stripped, nil, url.QueryEscape))
+		req.RequestURI = req.URL.RequestURI()
+	}
+	return true
+}
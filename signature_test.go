@@ -0,0 +1,138 @@
+package traefik_plugin_parameters_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestSignature_Valid(t *testing.T) {
+	cfg := newSignatureConfig()
+	rawQuery := "b=2&a=1&sig=" + sign("a=1&b=2")
+
+	req, recorder, called := serveSigned(t, cfg, rawQuery)
+
+	if !called {
+		t.Fatal("Expected next to be called")
+	}
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if expected := "b=2&a=1"; req.URL.RawQuery != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.RawQuery)
+	}
+}
+
+func TestSignature_SemicolonSeparator(t *testing.T) {
+	cfg := newSignatureConfig()
+	cfg.SemicolonSeparator = true
+	rawQuery := "b=2;a=1;sig=" + sign("a=1&b=2")
+
+	req, recorder, called := serveSigned(t, cfg, rawQuery)
+
+	if !called {
+		t.Fatal("Expected next to be called")
+	}
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if expected := "b=2;a=1"; req.URL.RawQuery != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.RawQuery)
+	}
+}
+
+func TestSignature_Invalid(t *testing.T) {
+	cfg := newSignatureConfig()
+	rawQuery := "a=1&b=3&sig=" + sign("a=1&b=2")
+
+	_, recorder, called := serveSigned(t, cfg, rawQuery)
+
+	if called {
+		t.Error("Expected next not to be called")
+	}
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestSignature_Missing(t *testing.T) {
+	cfg := newSignatureConfig()
+
+	_, recorder, called := serveSigned(t, cfg, "a=1&b=2")
+
+	if called {
+		t.Error("Expected next not to be called")
+	}
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestSignature_SignedParamsOnly(t *testing.T) {
+	cfg := newSignatureConfig()
+	cfg.SignedParams = []string{"a"}
+	cfg.SignatureParam = "signature"
+	rawQuery := "a=1&unsigned=x&signature=" + sign("a=1")
+
+	req, _, called := serveSigned(t, cfg, rawQuery)
+
+	if !called {
+		t.Fatal("Expected next to be called")
+	}
+	if expected := "a=1&unsigned=x"; req.URL.RawQuery != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.RawQuery)
+	}
+}
+
+func TestSignature_StrippedBeforeRules(t *testing.T) {
+	cfg := newSignatureConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "verified"
+	cfg.NewValue = "true"
+	rawQuery := "a=1&sig=" + sign("a=1")
+
+	req, _, _ := serveSigned(t, cfg, rawQuery)
+
+	if expected := "a=1&verified=true"; req.URL.RawQuery != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.RawQuery)
+	}
+}
+
+func TestSignature_ErrorWithoutSecret(t *testing.T) {
+	cfg := newSignatureConfig()
+	cfg.SignatureSecret = ""
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+func newSignatureConfig() *traefik_plugin_parameters.Config {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.VerifySignature = true
+	cfg.SignatureSecret = "secret"
+	return cfg
+}
+
+func sign(message string) string {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func serveSigned(t *testing.T, cfg *traefik_plugin_parameters.Config, rawQuery string) (*http.Request, *httptest.ResponseRecorder, bool) {
+	t.Helper()
+
+	called := false
+	handler := newHandler(t, cfg, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) { called = true }))
+	recorder, req := serve(handler, http.MethodGet, "http://localhost?"+rawQuery)
+	return req, recorder, called
+}
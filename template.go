@@ -0,0 +1,67 @@
+package traefik_plugin_parameters
+
+import (
+	"strings"
+	"sync/atomic"
+	"text/template"
+)
+
+// templateData is the data newValueTemplate is executed with
+type templateData struct {
+	Host       string
+	Path       string
+	Method     string
+	RemoteAddr string
+	// Name is the name of the param the value is rendered for
+	Name string
+	// Value is the old value for modify and empty for the types adding params
+	Value string
+	// NameGroups are the capture groups of paramNameRegex matching Name, starting with the whole match
+	NameGroups []string
+	// ValueGroups are the capture groups of paramValueRegex matching Value, starting with the whole match
+	ValueGroups []string
+	// Counter is a sequence number starting at 1, which is increased for every request rendering a template
+	// and shared by all values rendered for the same request
+	Counter uint64
+}
+
+// parseValueTemplate parses the given newValueTemplate, an empty template results in nil
+func parseValueTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New("newValueTemplate").Option("missingkey=error").Parse(text)
+}
+
+// counterValue returns the value of .Counter for the current request, which is drawn from the counter of the plugin instance
+// on first use, so requests not rendering a template leave the counter unchanged
+func (s *requestState) counterValue() uint64 {
+	if s.requestCounter == 0 && s.counter != nil {
+		s.requestCounter = atomic.AddUint64(s.counter, 1)
+	}
+	return s.requestCounter
+}
+
+// renderValue executes newValueTemplate for the param with the given name and old value.
+// On errors the old value is returned together with the error.
+func (r *rule) renderValue(state *requestState, name, value string) (string, error) {
+	data := templateData{Name: name, Value: value, Counter: state.counterValue()}
+	if req := state.req; req != nil {
+		data.Host = req.Host
+		data.Path = req.URL.Path
+		data.Method = req.Method
+		data.RemoteAddr = req.RemoteAddr
+	}
+	if r.paramNameRegexCompiled != nil {
+		data.NameGroups = r.paramNameRegexCompiled.FindStringSubmatch(name)
+	}
+	if r.paramValueRegexCompiled != nil {
+		data.ValueGroups = r.paramValueRegexCompiled.FindStringSubmatch(value)
+	}
+
+	var sb strings.Builder
+	if err := r.valueTemplate.Execute(&sb, data); err != nil {
+		return value, err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,163 @@
+package traefik_plugin_parameters_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestTemplate_AddHostAndPath(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "backend"
+	cfg.NewValueTemplate = "{{.Host}}{{.Path}}"
+	handler, err, recorder, req := createReqAndRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	req.Host = "example.com"
+	req.URL.Path = "/api/items"
+	req.URL.RawQuery = "a=1"
+	handler.ServeHTTP(recorder, req)
+
+	if expected := "a=1&backend=example.com%2Fapi%2Fitems"; req.URL.Query().Encode() != expected {
+		t.Errorf("Expected %s, got %s", expected, req.URL.Query().Encode())
+	}
+}
+
+func TestTemplate_TakesPrecedenceOverNewValue(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-or-replace"
+	cfg.ParamName = "m"
+	cfg.NewValue = "ignored"
+	cfg.NewValueTemplate = "{{.Method}}"
+	previous := "m=x"
+	expected := "m=GET"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTemplate_ModifyWithGroups(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamNameRegex = "^utm_(.*)$"
+	cfg.NewValueTemplate = `{{index .NameGroups 1}}:{{.Value}}`
+	previous := "utm_source=news&id=1"
+	expected := "id=1&utm_source=source%3Anews"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTemplate_ExecutionErrorLeavesValueUnchanged(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.NewValueTemplate = `{{index .NameGroups 1}}`
+	previous := "a=1"
+	expected := "a=1"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTemplate_ExecutionErrorSkipsAddIfAbsent(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add-if-absent"
+	cfg.ParamName = "b"
+	cfg.NewValueTemplate = `{{index .NameGroups 1}}`
+	handler := newHandler(t, cfg, nil)
+
+	result := handler.Apply(url.Values{"a": {"1"}})
+	if expected := (url.Values{"a": {"1"}}); !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestTemplate_ParseError(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "a"
+	cfg.NewValueTemplate = "{{.Host"
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+	if err == nil || !strings.HasPrefix(err.Error(), "newValueTemplate: ") {
+		t.Errorf("Expected template parse error, got %v", err)
+	}
+}
+
+func TestTemplate_CounterIncreasesPerRequest(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "add", ParamName: "seq", NewValueTemplate: "{{.Counter}}"},
+		{Type: "add", ParamName: "tag", NewValueTemplate: "req-{{.Counter}}"},
+	}
+	handler := newHandler(t, cfg, nil)
+
+	for i := 1; i <= 3; i++ {
+		result := handler.Apply(url.Values{})
+		expected := url.Values{"seq": {strconv.Itoa(i)}, "tag": {"req-" + strconv.Itoa(i)}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("expected %v, got %v", expected, result)
+		}
+	}
+}
+
+func TestTemplate_CounterUniqueAcrossConcurrentRequests(t *testing.T) {
+	const requests = 200
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "add"
+	cfg.ParamName = "seq"
+	cfg.NewValueTemplate = "{{.Counter}}"
+
+	var mu sync.Mutex
+	seen := make(map[int]bool, requests)
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		seq, err := strconv.Atoi(req.URL.Query().Get("seq"))
+		if err != nil {
+			t.Errorf("expected a numeric seq, got %q", req.URL.RawQuery)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if seen[seq] {
+			t.Errorf("seq %d was rendered twice", seq)
+		}
+		seen[seq] = true
+	})
+	handler := newHandler(t, cfg, next)
+
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve(handler, http.MethodGet, "http://localhost/?a=1")
+		}()
+	}
+	wg.Wait()
+
+	// the values of all requests form the sequence 1..requests without gaps
+	for seq := 1; seq <= requests; seq++ {
+		if !seen[seq] {
+			t.Errorf("seq %d was never rendered", seq)
+		}
+	}
+
+	previous := requests
+	for i := 0; i < 3; i++ {
+		_, req := serve(handler, http.MethodGet, "http://localhost/?a=1")
+		seq, _ := strconv.Atoi(req.URL.Query().Get("seq"))
+		if seq <= previous {
+			t.Errorf("expected seq to increase beyond %d, got %d", previous, seq)
+		}
+		previous = seq
+	}
+}
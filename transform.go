@@ -0,0 +1,159 @@
+package traefik_plugin_parameters
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type transformType string
+
+const (
+	base64EncodeTransform  transformType = "base64encode"
+	base64DecodeTransform  transformType = "base64decode"
+	urlEncodeTransform     transformType = "urlencode"
+	urlDecodeTransform     transformType = "urldecode"
+	sha256Transform        transformType = "sha256"
+	sha256TruncTransform   transformType = "sha256-truncated"
+	lowercaseTransform     transformType = "lowercase"
+	uppercaseTransform     transformType = "uppercase"
+	trimTransform          transformType = "trim"
+	clampTransform         transformType = "clamp"
+	clampMinTransform      transformType = "clampMin"
+	clampMaxTransform      transformType = "clampMax"
+	normalizeBoolTransform transformType = "normalizeBool"
+)
+
+// defaultTrueValues and defaultFalseValues are the tokens normalizeBool maps if trueValues or falseValues is not set
+var (
+	defaultTrueValues  = []string{"true", "1", "yes", "y", "on", "t"}
+	defaultFalseValues = []string{"false", "0", "no", "n", "off", "f"}
+)
+
+// apply transforms the given value using the options of the given rule configuration.
+// If the value cannot be transformed (e.g. invalid base64), an error is returned.
+func (t transformType) apply(value string, config *RuleConfig) (string, error) {
+	switch t {
+	case base64EncodeTransform:
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	case base64DecodeTransform:
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return value, err
+		}
+		return string(decoded), nil
+	case urlEncodeTransform:
+		return url.QueryEscape(value), nil
+	case urlDecodeTransform:
+		return url.QueryUnescape(value)
+	case sha256Transform:
+		return hashValue(value, config.HashSalt), nil
+	case sha256TruncTransform:
+		return hashValue(value, config.HashSalt)[:config.HashLength], nil
+	case lowercaseTransform:
+		return strings.ToLower(value), nil
+	case uppercaseTransform:
+		return strings.ToUpper(value), nil
+	case trimTransform:
+		return strings.TrimSpace(value), nil
+	case clampTransform, clampMinTransform, clampMaxTransform:
+		return t.clamp(value, config)
+	case normalizeBoolTransform:
+		return normalizeBool(value, config)
+	}
+
+	return value, nil
+}
+
+// clamp parses the value as an integer and limits it to minValue (for clamp and clampMin) and maxValue (for clamp and clampMax)
+func (t transformType) clamp(value string, config *RuleConfig) (string, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return value, err
+	}
+
+	if t != clampMaxTransform && n < config.MinValue {
+		n = config.MinValue
+	}
+	if t != clampMinTransform && n > config.MaxValue {
+		n = config.MaxValue
+	}
+	return strconv.Itoa(n), nil
+}
+
+// boolTokens returns the tokens normalizeBool maps to "true" and "false"
+func boolTokens(config *RuleConfig) ([]string, []string) {
+	trueValues, falseValues := config.TrueValues, config.FalseValues
+	if len(trueValues) == 0 {
+		trueValues = defaultTrueValues
+	}
+	if len(falseValues) == 0 {
+		falseValues = defaultFalseValues
+	}
+	return trueValues, falseValues
+}
+
+// normalizeBool maps the tokens of trueValues to "true" and the ones of falseValues to "false", ignoring case
+func normalizeBool(value string, config *RuleConfig) (string, error) {
+	trueValues, falseValues := boolTokens(config)
+	for _, token := range trueValues {
+		if strings.EqualFold(value, token) {
+			return "true", nil
+		}
+	}
+	for _, token := range falseValues {
+		if strings.EqualFold(value, token) {
+			return "false", nil
+		}
+	}
+	return value, fmt.Errorf("unknown boolean token %q", value)
+}
+
+// hashValue returns the hex encoded sha256 hash of the salted value
+func hashValue(value, salt string) string {
+	hash := sha256.Sum256([]byte(salt + value))
+	return hex.EncodeToString(hash[:])
+}
+
+// validate checks that the transform is known and the options it depends on are valid
+func (t transformType) validate(config *RuleConfig) error {
+	if !t.isValid() {
+		return errors.New("invalid transform, expected base64encode / base64decode / urlencode / urldecode / sha256 / sha256-truncated / lowercase / uppercase / trim / clamp / clampMin / clampMax / normalizeBool")
+	}
+
+	if t == sha256TruncTransform && (config.HashLength <= 0 || config.HashLength > sha256.Size*2) {
+		return fmt.Errorf("hashLength must be between 1 and %d for transform sha256-truncated", sha256.Size*2)
+	}
+
+	if t == clampTransform && config.MinValue > config.MaxValue {
+		return errors.New("minValue must not be greater than maxValue")
+	}
+
+	if t == normalizeBoolTransform {
+		trueValues, falseValues := boolTokens(config)
+		for _, trueValue := range trueValues {
+			for _, falseValue := range falseValues {
+				if strings.EqualFold(trueValue, falseValue) {
+					return fmt.Errorf("token %q must not be both in trueValues and falseValues", trueValue)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (t transformType) isValid() bool {
+	switch t {
+	case base64EncodeTransform, base64DecodeTransform, urlEncodeTransform, urlDecodeTransform,
+		sha256Transform, sha256TruncTransform, lowercaseTransform, uppercaseTransform, trimTransform, clampTransform, clampMinTransform, clampMaxTransform,
+		normalizeBoolTransform, "":
+		return true
+	}
+
+	return false
+}
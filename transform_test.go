@@ -0,0 +1,407 @@
+package traefik_plugin_parameters_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	traefik_plugin_parameters "github.com/dev-toolbox/traefik-plugin-parameters"
+)
+
+func TestTransform_Base64Encode(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.Transform = "base64encode"
+	previous := "a=hello&b=world"
+	expected := "a=aGVsbG8%3D&b=world"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_Lowercase(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "country"
+	cfg.Transform = "lowercase"
+	previous := "country=US&other=DE"
+	expected := "country=us&other=DE"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_UppercaseMultipleValues(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "country"
+	cfg.Transform = "uppercase"
+	previous := "country=us&country=De&country=FR"
+	expected := "country=US&country=DE&country=FR"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_LowercaseAfterRegexReplacement(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamValueRegex = "^LANG-(.*)$"
+	cfg.NewValueRegex = "$1"
+	cfg.Transform = "lowercase"
+	previous := "lang=LANG-EN"
+	expected := "lang=en"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_Trim(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "id"
+	cfg.Transform = "trim"
+	previous := "id=+123+&id=%09456&other=+1+"
+	expected := "id=123&id=456&other=+1+"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_TrimAfterReplacement(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamValueRegex = "^id:(.*)$"
+	cfg.NewValueRegex = "$1"
+	cfg.Transform = "trim"
+	previous := "a=id%3A+123+"
+	expected := "a=123"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_Clamp(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "limit"
+	cfg.Transform = "clamp"
+	cfg.MinValue = 1
+	cfg.MaxValue = 100
+
+	assertQueryModification(t, cfg, "limit=0", "limit=1")
+	assertQueryModification(t, cfg, "limit=-5", "limit=1")
+	assertQueryModification(t, cfg, "limit=1000", "limit=100")
+	assertQueryModification(t, cfg, "limit=42", "limit=42")
+	assertQueryModification(t, cfg, "limit=all&limit=500", "limit=all&limit=100")
+}
+
+func TestTransform_ClampMinOnly(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "limit"
+	cfg.Transform = "clampMin"
+	cfg.MinValue = 1
+
+	assertQueryModification(t, cfg, "limit=0", "limit=1")
+	assertQueryModification(t, cfg, "limit=1000", "limit=1000")
+}
+
+func TestTransform_ClampMaxOnly(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "limit"
+	cfg.Transform = "clampMax"
+	cfg.MaxValue = 100
+
+	assertQueryModification(t, cfg, "limit=-5", "limit=-5")
+	assertQueryModification(t, cfg, "limit=1000", "limit=100")
+}
+
+func TestTransform_Base64Decode(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.Transform = "base64decode"
+	previous := "a=aGVsbG8%3D"
+	expected := "a=hello"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_Base64DecodeMalformed(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.Transform = "base64decode"
+	previous := "a=not-base64!&a=aGVsbG8%3D"
+	expected := "a=not-base64%21&a=hello"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_AfterReplacement(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.NewValue = "user:$1"
+	cfg.Transform = "base64encode"
+	previous := "a=john"
+	expected := "a=dXNlcjpqb2hu"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_URLEncodeDecode(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Rules = []traefik_plugin_parameters.RuleConfig{
+		{Type: "modify", ParamName: "encoded", Transform: "urlencode"},
+		{Type: "modify", ParamName: "decoded", Transform: "urldecode"},
+	}
+	previous := "encoded=a%26b&decoded=a%2526b"
+	expected := "decoded=a%26b&encoded=a%2526b"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_SHA256(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "uid"
+	cfg.Transform = "sha256"
+	previous := "uid=42"
+	expected := "uid=73475cb40a568e8da8a045ced110137e159f890ac4da883b6b17dc651b3a8049"
+
+	assertQueryModification(t, cfg, previous, expected)
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_SHA256Salted(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "uid"
+	cfg.Transform = "sha256"
+	cfg.HashSalt = "salt"
+	previous := "uid=42"
+	expected := "uid=ba5bf48c9d94fef61432ae21b346d1307be9476c9c8e98d111366abbb69d45cd"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_SHA256Truncated(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "uid"
+	cfg.Transform = "sha256-truncated"
+	cfg.HashLength = 12
+	previous := "uid=42"
+	expected := "uid=73475cb40a56"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestErrorSHA256TruncatedLength(t *testing.T) {
+	for _, hashLength := range []int{0, 65} {
+		cfg := traefik_plugin_parameters.CreateConfig()
+		cfg.Type = "modify"
+		cfg.ParamName = "uid"
+		cfg.Transform = "sha256-truncated"
+		cfg.HashLength = hashLength
+		next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+		_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+		if err == nil {
+			t.Errorf("expected error for hashLength %d but err is nil", hashLength)
+		}
+	}
+}
+
+func TestErrorInvalidTransform(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.Transform = "rot13"
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	_, err := traefik_plugin_parameters.New(context.Background(), next, cfg, "query-modification-plugin")
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+func TestTransforms_OrderMatters(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.Transforms = []string{"base64encode", "lowercase"}
+
+	assertQueryModification(t, cfg, "a=Hi", "a=sgk%3D")
+
+	cfg.Transforms = []string{"lowercase", "base64encode"}
+	assertQueryModification(t, cfg, "a=Hi", "a=aGk%3D")
+}
+
+func TestTransforms_AfterRegexReplacement(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamValueRegex = "^(.*)-x$"
+	cfg.NewValueRegex = "$1"
+	cfg.Transforms = []string{"trim", "uppercase"}
+	previous := "a=+ab+-x&b=cd"
+	expected := "a=AB&b=cd"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransforms_FailureLeavesValueUnchanged(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.Transforms = []string{"uppercase", "base64decode"}
+	previous := "a=not-base64"
+	expected := "a=not-base64"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransforms_Errors(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		transforms []string
+	}{
+		{desc: "invalid name", transforms: []string{"trim", "reverse"}},
+		{desc: "empty name", transforms: []string{""}},
+		{desc: "missing hashLength", transforms: []string{"sha256-truncated"}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.Type = "modify"
+			cfg.ParamName = "a"
+			cfg.Transforms = test.transforms
+			_, err := traefik_plugin_parameters.New(context.Background(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), cfg, "query-modification-plugin")
+
+			if err == nil {
+				t.Error("expected error but err is nil")
+			}
+		})
+	}
+}
+
+func TestTransforms_ErrorTogetherWithTransform(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "a"
+	cfg.Transform = "trim"
+	cfg.Transforms = []string{"lowercase"}
+	_, err, _, _ := createReqAndRecorder(cfg)
+
+	if err == nil {
+		t.Error("expected error but err is nil")
+	}
+}
+
+func TestTransform_NormalizeBool(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "flag"
+	cfg.Transform = "normalizeBool"
+	previous := "flag=1&flag=yes&flag=ON&flag=0&flag=no&flag=off&other=1"
+	expected := "flag=true&flag=true&flag=true&flag=false&flag=false&flag=false&other=1"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_NormalizeBoolUnknownToken(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "flag"
+	cfg.Transform = "normalizeBool"
+
+	assertQueryModification(t, cfg, "flag=maybe&flag=yes", "flag=maybe&flag=true")
+
+	logged := serveAndCaptureLog(t, cfg, "flag=maybe")
+	if !strings.Contains(logged, "unknown boolean token") {
+		t.Errorf("Expected warning about the unknown token, got %s", logged)
+	}
+}
+
+func TestTransform_NormalizeBoolCustomTokens(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "flag"
+	cfg.Transform = "normalizeBool"
+	cfg.TrueValues = []string{"enabled"}
+	cfg.FalseValues = []string{"disabled"}
+	previous := "flag=Enabled&flag=disabled&flag=1"
+	expected := "flag=true&flag=false&flag=1"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestTransform_NormalizeBoolErrors(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config traefik_plugin_parameters.RuleConfig
+	}{
+		{desc: "token in both lists", config: traefik_plugin_parameters.RuleConfig{Type: "modify", ParamName: "a", Transform: "normalizeBool", TrueValues: []string{"x", "no"}}},
+		{desc: "tokens without normalizeBool", config: traefik_plugin_parameters.RuleConfig{Type: "modify", ParamName: "a", Transform: "trim", TrueValues: []string{"x"}}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := traefik_plugin_parameters.CreateConfig()
+			cfg.RuleConfig = test.config
+			_, err, _, _ := createReqAndRecorder(cfg)
+
+			if err == nil {
+				t.Error("expected error but err is nil")
+			}
+		})
+	}
+}
+
+func TestValueAffixes_Prefix(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "callback"
+	cfg.ValuePrefix = "https://"
+	previous := "callback=example.com&callback=example.org&other=1"
+	expected := "callback=https%3A%2F%2Fexample.com&callback=https%3A%2F%2Fexample.org&other=1"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestValueAffixes_Suffix(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamName = "file"
+	cfg.ValueSuffix = ".json"
+	previous := "file=data"
+	expected := "file=data.json"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestValueAffixes_AfterTransformAndReplacement(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "modify"
+	cfg.ParamValueRegex = "^v(\\w+)$"
+	cfg.NewValueRegex = "$1"
+	cfg.Transform = "uppercase"
+	cfg.ValuePrefix = "version-"
+	cfg.ValueSuffix = "-X"
+	previous := "a=vab&b=wab"
+	expected := "a=version-AB-X&b=wab"
+
+	assertQueryModification(t, cfg, previous, expected)
+}
+
+func TestValueAffixes_OnlyWithModify(t *testing.T) {
+	cfg := traefik_plugin_parameters.CreateConfig()
+	cfg.Type = "delete"
+	cfg.ParamName = "a"
+	cfg.ValuePrefix = "x"
+
+	err := traefik_plugin_parameters.ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "valuePrefix and valueSuffix can only be used with the modify type") {
+		t.Errorf("expected an error about valuePrefix, got %v", err)
+	}
+}